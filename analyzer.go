@@ -0,0 +1,654 @@
+package clojura
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Var is the cell holding the value of a global. Analyzed code refers to the
+// cell directly instead of looking the name up, so it sees definitions made
+// after it was analyzed. A var that was declared but not defined yet holds
+// no value. A private var can only be referred to from its own namespace.
+type Var struct {
+	ns      *Namespace
+	name    Literal
+	value   Sexpr
+	private bool
+}
+
+func (v *Var) Type() CoreType {
+	return TypeVar
+}
+
+func (v *Var) Bool() bool {
+	return true
+}
+
+func (v *Var) String() string {
+	return "#'" + string(v.ns.name) + "/" + string(v.name)
+}
+
+func (v *Var) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (v *Var) Eval(c *Context) Sexpr {
+	if v.value == nil {
+		throwf("Var %s is unbound", v.name)
+	}
+	return v.value
+}
+
+// local refers to a local binding by the number of frames to go up from
+// the current one and its slot in that frame.
+type local struct {
+	name  Literal
+	depth int
+	index int
+}
+
+func (l *local) Type() CoreType {
+	return TypeExpression
+}
+
+func (l *local) Bool() bool {
+	return true
+}
+
+func (l *local) String() string {
+	return string(l.name)
+}
+
+func (l *local) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (l *local) Eval(c *Context) Sexpr {
+	for i := 0; i < l.depth; i++ {
+		c = c.parent
+	}
+	return c.slots[l.index]
+}
+
+// constant is a quoted form, which evaluates to its data as it is.
+type constant struct {
+	val Sexpr
+}
+
+func (k *constant) Type() CoreType {
+	return TypeExpression
+}
+
+func (k *constant) Bool() bool {
+	return true
+}
+
+func (k *constant) String() string {
+	return "(quote " + k.val.String() + ")"
+}
+
+func (k *constant) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (k *constant) Eval(c *Context) Sexpr {
+	return k.val
+}
+
+// node holds what all analyzed special forms have in common: the form they
+// were analyzed from, which is how they print.
+type node struct {
+	form *Expression
+}
+
+func (n node) Type() CoreType {
+	return TypeExpression
+}
+
+func (n node) Bool() bool {
+	return true
+}
+
+func (n node) String() string {
+	return n.form.String()
+}
+
+func (n node) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+// defNode binds a global.
+type defNode struct {
+	node
+	v   *Var
+	val Sexpr
+}
+
+func (d *defNode) Eval(c *Context) Sexpr {
+	res := d.val.Eval(c)
+	d.v.value = res
+	return res
+}
+
+// fnNode creates a function closing over the frame it is evaluated in.
+type fnNode struct {
+	node
+	name    string
+	arities []*arity
+}
+
+func (f *fnNode) Eval(c *Context) Sexpr {
+	return &function{name: f.name, arities: f.arities, context: c}
+}
+
+// letNode evaluates its body in a new frame holding its bindings.
+type letNode struct {
+	node
+	bindings []binding
+	inits    []Sexpr
+	size     int
+	body     []Sexpr
+}
+
+func (l *letNode) Eval(c *Context) Sexpr {
+	return force(l.eval(c, false))
+}
+
+func (l *letNode) eval(c *Context, tail bool) Sexpr {
+	frame := newFrame(c, l.size)
+	for i, b := range l.bindings {
+		b.bind(frame, l.inits[i].Eval(frame))
+	}
+	return evalBody(frame, l.body)
+}
+
+// letfnNode evaluates its body in a new frame holding functions which can
+// all call each other.
+type letfnNode struct {
+	node
+	fns  []*fnNode
+	size int
+	body []Sexpr
+}
+
+func (l *letfnNode) Eval(c *Context) Sexpr {
+	return force(l.eval(c, false))
+}
+
+func (l *letfnNode) eval(c *Context, tail bool) Sexpr {
+	frame := newFrame(c, l.size)
+	for i, f := range l.fns {
+		frame.slots[i] = f.Eval(frame)
+	}
+	return evalBody(frame, l.body)
+}
+
+// scope holds the names of the locals of a frame while it is analyzed. A
+// name bound again gets a new slot that shadows the old one.
+type scope struct {
+	names  map[Literal]int
+	size   int
+	parent *scope
+}
+
+func (s *scope) add(name Literal) int {
+	s.names[name] = s.size
+	s.size++
+	return s.size - 1
+}
+
+// analyzer turns parsed forms into forms that are ready to be evaluated:
+// locals are resolved to slots, globals to vars, macros are expanded and
+// special forms are checked. Every symbol has to resolve, so mistakes are
+// reported before any code runs.
+type analyzer struct {
+	in    *Interpreter
+	scope *scope
+	// recur is the number of arguments a recur takes in the innermost
+	// function or loop, or -1 outside of them.
+	recur int
+	// pos is the position of the innermost list being analyzed, or of the
+	// top-level form when none is.
+	pos Pos
+}
+
+// analyze analyzes a top-level form.
+func (in *Interpreter) analyze(form Sexpr) Sexpr {
+	return in.analyzeAt(form, Pos{})
+}
+
+// analyzeAt analyzes a top-level form read at pos.
+func (in *Interpreter) analyzeAt(form Sexpr, pos Pos) Sexpr {
+	a := &analyzer{in: in, recur: -1, pos: pos}
+	return a.analyze(form, false)
+}
+
+// errorf raises an exception with a formatted message, prefixed with the
+// position of the form being analyzed.
+func (a *analyzer) errorf(format string, args ...interface{}) {
+	throwf("%s: %s", a.pos, fmt.Sprintf(format, args...))
+}
+
+// checkArity raises an exception unless exactly n arguments were passed to
+// the special form name.
+func (a *analyzer) checkArity(name string, args []Sexpr, n int) {
+	if len(args) != n {
+		a.errorf("Wrong number of args (%d) passed to %s", len(args), name)
+	}
+}
+
+func (a *analyzer) push() {
+	a.scope = &scope{names: map[Literal]int{}, parent: a.scope}
+}
+
+func (a *analyzer) pop() {
+	a.scope = a.scope.parent
+}
+
+// analyze analyzes form. tail tells whether form is in tail position of the
+// innermost function or loop.
+func (a *analyzer) analyze(form Sexpr, tail bool) Sexpr {
+	switch t := form.(type) {
+	case Literal:
+		return a.resolve(t)
+	case *Expression:
+		return a.analyzeList(t, tail)
+	case *List:
+		return a.analyzeList(&Expression{Elements: sequentialItems(t), Pos: a.pos}, tail)
+	case *Vector:
+		return NewVector(a.analyzeAll(t.Slice())...)
+	case *HashMap:
+		res := emptyMap
+		t.Each(func(key, val Sexpr) {
+			res = res.Assoc(a.analyze(key, false), a.analyze(val, false))
+		})
+		return res
+	case *Set:
+		return NewSet(a.analyzeAll(t.Slice())...)
+	}
+	return form
+}
+
+func (a *analyzer) analyzeAll(forms []Sexpr) []Sexpr {
+	res := make([]Sexpr, len(forms))
+	for i, form := range forms {
+		res[i] = a.analyze(form, false)
+	}
+	return res
+}
+
+// analyzeBody analyzes a body whose last form is in tail position when the
+// body itself is.
+func (a *analyzer) analyzeBody(forms []Sexpr, tail bool) []Sexpr {
+	res := make([]Sexpr, len(forms))
+	for i, form := range forms {
+		res[i] = a.analyze(form, tail && i == len(forms)-1)
+	}
+	return res
+}
+
+// lookup finds a local by name.
+func (a *analyzer) lookup(name Literal) (*local, bool) {
+	depth := 0
+	for s := a.scope; s != nil; s = s.parent {
+		if i, ok := s.names[name]; ok {
+			return &local{name: name, depth: depth, index: i}, true
+		}
+		depth++
+	}
+	return nil, false
+}
+
+func (a *analyzer) resolve(name Literal) Sexpr {
+	if l, ok := a.lookup(name); ok {
+		return l
+	}
+	v, err := a.in.lookupVar(name)
+	if err != nil {
+		a.errorf("%v", err)
+	}
+	if v == nil {
+		a.errorf("Unable to resolve symbol: %s in this context", name)
+	}
+	return v
+}
+
+// specialForms are the forms the analyzer turns into nodes of their own.
+// Unlike if or do they are not bound in the root context.
+var specialForms = map[Literal]bool{
+	"def":          true,
+	"declare":      true,
+	"fn":           true,
+	"let":          true,
+	"letfn":        true,
+	"loop":         true,
+	"quote":        true,
+	"syntax-quote": true,
+	"defmacro":     true,
+	"try":          true,
+	"defn":         true,
+	"defn-":        true,
+	"ns":           true,
+}
+
+func (a *analyzer) analyzeList(e *Expression, tail bool) Sexpr {
+	if len(e.Elements) == 0 {
+		return e
+	}
+	pos := a.pos
+	if e.Pos != (Pos{}) {
+		a.pos = e.Pos
+	}
+	defer func() {
+		a.pos = pos
+	}()
+
+	name, _ := e.Elements[0].(Literal)
+	args := e.Elements[1:]
+	switch name {
+	case "def":
+		return a.analyzeDef(e)
+	case "defn", "defn-":
+		return a.analyzeDefn(e, name == "defn-")
+	case "ns":
+		return a.analyzeNs(e)
+	case "declare":
+		for _, arg := range args {
+			n, ok := arg.(Literal)
+			if !ok {
+				a.errorf("declare name should be a literal, got %s", arg)
+			}
+			a.in.ns.intern(n)
+		}
+		return Nil
+	case "fn":
+		return a.analyzeFn(e)
+	case "let":
+		return a.analyzeLet(e, tail)
+	case "letfn":
+		return a.analyzeLetfn(e, tail)
+	case "loop":
+		return a.analyzeLoop(e)
+	case "quote":
+		a.checkArity("quote", args, 1)
+		return &constant{val: formToData(args[0])}
+	case "syntax-quote":
+		a.checkArity("syntax-quote", args, 1)
+		return &syntaxQuoteNode{node: node{e}, template: a.template(args[0])}
+	case "defmacro":
+		return a.analyzeDefmacro(e)
+	case "try":
+		return a.analyzeTry(e)
+	case "unquote", "unquote-splicing":
+		a.errorf("%s used outside of syntax-quote", name)
+	case "recur":
+		if !tail || a.recur < 0 {
+			a.errorf("Can only recur from tail position")
+		}
+		if len(args) != a.recur {
+			a.errorf("Mismatched argument count to recur, expected: %d args, got: %d",
+				a.recur, len(args))
+		}
+		return a.call(e, nil)
+	case "if":
+		// The test is not in tail position, the branches are.
+		return a.call(e, func(i int) bool {
+			return tail && i > 0
+		})
+	case "do", "and", "or":
+		return a.call(e, func(i int) bool {
+			return tail && i == len(args)-1
+		})
+	}
+
+	if _, ok := a.lookup(name); !ok && name != "" {
+		if v, _ := a.in.lookupVar(name); v != nil {
+			if m, ok := v.value.(*macro); ok {
+				return a.analyze(m.expandCall(a.in, args, e.Pos), tail)
+			}
+		}
+	}
+	return a.call(e, nil)
+}
+
+// call analyzes a call. inTail tells which arguments are in tail position
+// when the called value is a special form that returns them.
+func (a *analyzer) call(e *Expression, inTail func(i int) bool) Sexpr {
+	res := &Expression{Elements: make([]Sexpr, len(e.Elements)), Pos: e.Pos}
+	res.Elements[0] = a.analyze(e.Elements[0], false)
+	for i, arg := range e.Elements[1:] {
+		res.Elements[i+1] = a.analyze(arg, inTail != nil && inTail(i))
+	}
+	return res
+}
+
+func (a *analyzer) analyzeDef(e *Expression) Sexpr {
+	n, val := bindingArgs("def", e.Elements)
+	if _, _, ok := splitSymbol(n); ok {
+		a.errorf("Can't def a qualified name: %s", n)
+	}
+	v := a.in.ns.intern(n)
+	res := &defNode{node: node{e}, v: v, val: a.analyze(val, false)}
+	if f, ok := res.val.(*fnNode); ok && f.name == "" {
+		f.name = string(n)
+	}
+	return res
+}
+
+// analyzeDefn analyzes (defn name doc? fn-tail), which is
+// (def name (fn name fn-tail)). defn- defines a private var.
+func (a *analyzer) analyzeDefn(e *Expression, private bool) Sexpr {
+	if len(e.Elements) < 2 {
+		a.errorf("Wrong number of args (0) passed to %s", e.Elements[0])
+	}
+	rest := e.Elements[2:]
+	if len(rest) > 0 {
+		if _, ok := rest[0].(String); ok {
+			rest = rest[1:]
+		}
+	}
+	fn := &Expression{Elements: append([]Sexpr{Literal("fn"), e.Elements[1]}, rest...), Pos: e.Pos}
+	res := a.analyzeDef(&Expression{Elements: []Sexpr{Literal("def"), e.Elements[1], fn}, Pos: e.Pos})
+	res.(*defNode).v.private = private
+	return res
+}
+
+// analyzeFn analyzes (fn name? [params] body*) and
+// (fn name? ([params] body*)+).
+func (a *analyzer) analyzeFn(e *Expression) *fnNode {
+	args := e.Elements[1:]
+	var name Literal
+	if len(args) > 0 {
+		if n, ok := args[0].(Literal); ok {
+			name = n
+			args = args[1:]
+		}
+	}
+	if len(args) == 0 {
+		a.errorf("fn should have an argument list and a body")
+	}
+
+	res := &fnNode{node: node{e}, name: string(name)}
+	if !isArityForm(args[0]) {
+		if len(args) < 2 {
+			a.errorf("fn should have an argument list and a body")
+		}
+		res.arities = []*arity{a.analyzeArity(name, args[0], args[1:])}
+		return res
+	}
+	for _, form := range args {
+		if !isArityForm(form) {
+			a.errorf("fn arity should be a list starting with an argument vector, got %s", form)
+		}
+		elements := form.(*Expression).Elements
+		res.arities = append(res.arities, a.analyzeArity(name, elements[0], elements[1:]))
+	}
+	a.checkArities(res.arities)
+	return res
+}
+
+// isArityForm reports whether s is one ([params] body*) body of a
+// multi-arity fn.
+func isArityForm(s Sexpr) bool {
+	e, ok := s.(*Expression)
+	if !ok || len(e.Elements) == 0 {
+		return false
+	}
+	_, ok = e.Elements[0].(*Vector)
+	return ok
+}
+
+// analyzeArity analyzes one body of a function. A named function gets
+// itself in the first slot of its frame.
+func (a *analyzer) analyzeArity(name Literal, argp Sexpr, body []Sexpr) *arity {
+	var params []Sexpr
+	switch t := argp.(type) {
+	case *Vector:
+		params = t.Slice()
+	case *Expression:
+		params = t.Elements
+	default:
+		a.errorf("fn arguments should be a vector, got %s", argp)
+	}
+
+	a.push()
+	defer a.pop()
+	res := &arity{}
+	if name != "" {
+		a.scope.add(name)
+		res.self = true
+	}
+	sig := make([]string, len(params))
+	for i, p := range params {
+		sig[i] = p.String()
+	}
+	res.sig = "[" + strings.Join(sig, " ") + "]"
+	for i := 0; i < len(params); i++ {
+		if params[i] != Literal("&") {
+			res.params = append(res.params, a.binding(params[i]))
+			continue
+		}
+		if i != len(params)-2 {
+			a.errorf("& should be followed by a single parameter")
+		}
+		res.rest = a.binding(params[i+1])
+		break
+	}
+
+	recur := a.recur
+	a.recur = len(res.params)
+	if res.rest != nil {
+		a.recur++
+	}
+	res.body = a.analyzeBody(body, true)
+	a.recur = recur
+	res.size = a.scope.size
+	return res
+}
+
+// checkArities rejects the arities a multi-arity function can't dispatch
+// between.
+func (a *analyzer) checkArities(arities []*arity) {
+	fixed := map[int]bool{}
+	var variadic *arity
+	for _, ar := range arities {
+		if ar.variadic() {
+			if variadic != nil {
+				a.errorf("Can't have more than 1 variadic overload")
+			}
+			variadic = ar
+		} else if fixed[len(ar.params)] {
+			a.errorf("Can't have 2 overloads with same arity")
+		} else {
+			fixed[len(ar.params)] = true
+		}
+	}
+	if variadic != nil {
+		for n := range fixed {
+			if n > len(variadic.params) {
+				a.errorf("Can't have fixed arity function with more params than variadic function")
+			}
+		}
+	}
+}
+
+// bindingVector returns the binding vector of a let-like form.
+func (a *analyzer) bindingVector(name string, e *Expression) []Sexpr {
+	if len(e.Elements) < 2 {
+		a.errorf("%s requires a binding vector", name)
+	}
+	bindings, ok := e.Elements[1].(*Vector)
+	if !ok {
+		a.errorf("%s requires a vector for its binding, got %s", name, e.Elements[1])
+	}
+	return bindings.Slice()
+}
+
+// analyzeLet analyzes (let [binding-form value*] body*). Every value can
+// refer to the names bound before it.
+func (a *analyzer) analyzeLet(e *Expression, tail bool) Sexpr {
+	items := a.bindingVector("let", e)
+	if len(items)%2 != 0 {
+		a.errorf("let requires an even number of forms in binding vector")
+	}
+	a.push()
+	defer a.pop()
+	res := &letNode{node: node{e}}
+	for i := 0; i < len(items); i += 2 {
+		res.inits = append(res.inits, a.analyze(items[i+1], false))
+		res.bindings = append(res.bindings, a.binding(items[i]))
+	}
+	res.body = a.analyzeBody(e.Elements[2:], tail)
+	res.size = a.scope.size
+	return res
+}
+
+// analyzeLetfn analyzes (letfn [(name [params] body*)*] body*).
+func (a *analyzer) analyzeLetfn(e *Expression, tail bool) Sexpr {
+	specs := a.bindingVector("letfn", e)
+	a.push()
+	defer a.pop()
+	for _, spec := range specs {
+		s, ok := spec.(*Expression)
+		if !ok || len(s.Elements) == 0 {
+			a.errorf("letfn binding should be a (name [params] body*) list, got %s", spec)
+		}
+		name, ok := s.Elements[0].(Literal)
+		if !ok {
+			a.errorf("letfn name should be a literal, got %s", s.Elements[0])
+		}
+		a.scope.add(name)
+	}
+	res := &letfnNode{node: node{e}}
+	for _, spec := range specs {
+		s := spec.(*Expression)
+		fn := &Expression{Elements: append([]Sexpr{Literal("fn")}, s.Elements...), Pos: s.Pos}
+		res.fns = append(res.fns, a.analyzeFn(fn))
+	}
+	res.body = a.analyzeBody(e.Elements[2:], tail)
+	res.size = a.scope.size
+	return res
+}
+
+// analyzeLoop analyzes (loop [binding-form value*] body*), whose body is
+// the target of the recurs in its tail position.
+func (a *analyzer) analyzeLoop(e *Expression) Sexpr {
+	items := a.bindingVector("loop", e)
+	if len(items)%2 != 0 {
+		a.errorf("loop requires an even number of forms in binding vector")
+	}
+	a.push()
+	defer a.pop()
+	res := &loopNode{node: node{e}}
+	for i := 0; i < len(items); i += 2 {
+		res.inits = append(res.inits, a.analyze(items[i+1], false))
+		res.bindings = append(res.bindings, a.binding(items[i]))
+	}
+	recur := a.recur
+	a.recur = len(res.bindings)
+	res.body = a.analyzeBody(e.Elements[2:], true)
+	a.recur = recur
+	res.size = a.scope.size
+	return res
+}
@@ -0,0 +1,43 @@
+package clojura
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAnalyzer(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	tests := map[string]string{
+		"(let [x 1 x (+ x 1)] x)":                                                            "2",
+		"(((fn [a] (fn [b] (+ a b))) 1) 2)":                                                  "3",
+		"((fn fact [n] (if (< n 2) 1 (* n (fact (- n 1))))) 5)":                              "120",
+		"(let [a 1] (let [b 2] (let [c 3] [a b c])))":                                        "[1 2 3]",
+		"(loop [i 0 fs []] (if (< i 3) (recur (+ i 1) (conj fs (fn [] i))) ((nth fs 1))))":   "1",
+		"(do (declare later) (def early (fn [] (later))) (def later (fn [] :late)) (early))": ":late",
+	}
+	checkEval(t, in, tests)
+
+	errors := map[string]string{
+		"(declare unbound) (unbound)":  "Var unbound is unbound",
+		"(let [f (fn [] (nope))] (f))": "NO_SOURCE_FILE:1:16: Unable to resolve symbol: nope in this context",
+		"(loop [a 1 b 2] (recur 1))":   "Mismatched argument count to recur, expected: 2 args, got: 1",
+		"1\n  nope":                    "NO_SOURCE_FILE:2:3: Unable to resolve symbol: nope in this context",
+		"[1 nope]":                     "NO_SOURCE_FILE:1:1: Unable to resolve symbol: nope in this context",
+		"\n(let [x] x)":                "NO_SOURCE_FILE:2:1: let requires an even number of forms in binding vector",
+		"(do\n (fn [a & b c] 1))":      "NO_SOURCE_FILE:2:2: & should be followed by a single parameter",
+		"(fn ([a] 1) ([b] 2))":         "NO_SOURCE_FILE:1:1: Can't have 2 overloads with same arity",
+		"(let [{:keys x} {}] x)":       "NO_SOURCE_FILE:1:1: :keys argument should be a vector, got x",
+	}
+	checkEvalErrors(t, in, errors)
+}
+
+func TestAnalyzerRunsNothingOnError(t *testing.T) {
+	var out bytes.Buffer
+	in := NewInterpreter(&out, &out)
+	if _, err := in.Eval(`(let [] (println "ran") (fn [] (nope)))`); err == nil {
+		t.Fatal("expected an unresolved symbol error")
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected nothing to run, got %q", out.String())
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	slog "log"
+	"os"
+	"path/filepath"
+
+	"github.com/stakhiv/clojura"
+)
+
+func main() {
+	vm := flag.Bool("vm", false, "compile code to bytecode and run it on the virtual machine")
+	path := flag.String("path", "", "directories to load files from, separated by "+string(filepath.ListSeparator))
+	flag.Parse()
+	in := clojura.New()
+	in.UseVM(*vm)
+	// Directories given with -path are searched before the ones of
+	// CLOJURA_PATH.
+	dirs := filepath.SplitList(*path)
+	in.SetLoadPath(append(dirs, filepath.SplitList(os.Getenv("CLOJURA_PATH"))...))
+	if len(flag.Args()) < 1 {
+		StartRepl(in)
+		return
+	}
+
+	fname := flag.Args()[0]
+	err := in.LoadFile(fname)
+	if e, ok := err.(*clojura.Exception); ok {
+		e.PrintStackTrace(os.Stderr)
+		os.Exit(1)
+	} else if err != nil {
+		slog.Fatalf("Failed to load '%s': %v", fname, err)
+	}
+}
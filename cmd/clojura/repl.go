@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/peterh/liner"
+	"github.com/stakhiv/clojura"
+)
+
+func StartRepl(in *clojura.Interpreter) {
+	line := liner.NewLiner()
+	defer line.Close()
+
+	r := strings.NewReplacer("(", "", ")", "")
+	line.SetCompleter(func(line string) (c []string) {
+		word := line
+		words := strings.Split(line, " ")
+		if len(words) > 0 {
+			word = words[len(words)-1]
+		}
+		word = r.Replace(word)
+		for _, name := range in.Names() {
+			if strings.HasPrefix(name, strings.ToLower(word)) {
+				c = append(c, line[:len(line)-len(word)]+name)
+			}
+		}
+		return
+	})
+
+	for {
+		if text, err := line.Prompt(in.Namespace() + "> "); err == nil {
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			line.AppendHistory(text)
+			res, err := in.Eval(text)
+			var out string
+			if err == nil {
+				// Printing realizes lazy sequences, which may fail.
+				out, err = in.Show(res)
+			}
+			if e, ok := err.(*clojura.Exception); ok {
+				e.PrintStackTrace(os.Stdout)
+				continue
+			} else if err != nil {
+				fmt.Println("Error:", err)
+				continue
+			}
+			fmt.Println(">>", out)
+		} else if err == io.EOF {
+			fmt.Println("\nExiting...")
+			return
+		} else if err == liner.ErrPromptAborted {
+			fmt.Println("Aborted")
+			break
+		} else {
+			fmt.Println("Error reading line: ", err)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package clojura
+
+// opcode is an instruction of the VM. Its operands follow it in the code,
+// each stored in two bytes.
+type opcode byte
+
+const (
+	// opConst pushes constant a.
+	opConst opcode = iota
+	// opLocal pushes slot b of the frame a levels up from the current one.
+	opLocal
+	// opVar pushes the value of var a.
+	opVar
+	// opDef binds var a to the value on top of the stack, leaving it there.
+	opDef
+	// opPop drops the value on top of the stack.
+	opPop
+	// opJump jumps to a.
+	opJump
+	// opJumpFalse pops a value and jumps to a if it is false or nil.
+	opJumpFalse
+	// opAnd jumps to a if the value on top of the stack is false or nil,
+	// and drops the value otherwise.
+	opAnd
+	// opOr jumps to a if the value on top of the stack is true, and drops
+	// the value otherwise.
+	opOr
+	// opCall calls the function below the a values on top of the stack
+	// with them, from call site b.
+	opCall
+	// opTailCall is opCall in tail position of a function: the call is left
+	// to the caller of the function.
+	opTailCall
+	// opClosure pushes a function made from fn a, closing over the frame.
+	opClosure
+	// opVector replaces the a values on top of the stack with a vector.
+	opVector
+	// opMap replaces the a key value pairs on top of the stack with a map.
+	opMap
+	// opEnter enters a new frame with a slots.
+	opEnter
+	// opLeave leaves the frame for its parent.
+	opLeave
+	// opBind pops a value and binds it with binding a.
+	opBind
+	// opRecur runs the function again with the a values on top of the
+	// stack as its arguments.
+	opRecur
+	// opLoop runs loop a again with the values on top of the stack bound,
+	// leaving the b frames entered since the loop started.
+	opLoop
+	// opEval pushes the value of constant a, evaluated by the tree walker.
+	opEval
+	// opReturn returns the value on top of the stack.
+	opReturn
+)
+
+// chunk is compiled code along with the tables its instructions refer to.
+// The code of a function arity runs in the frame the arity is entered with.
+type chunk struct {
+	code     []byte
+	consts   []Sexpr
+	vars     []*Var
+	bindings []binding
+	fns      []*fnNode
+	loops    []loopInfo
+	sites    []callSite
+	arity    *arity
+}
+
+// loopInfo is a loop along with where its body starts.
+type loopInfo struct {
+	node  *loopNode
+	start int
+}
+
+// callSite is where a call is made and the name the called function is
+// shown with in stack traces unless it has a name of its own.
+type callSite struct {
+	head Sexpr
+	name string
+	pos  Pos
+}
+
+// recurTarget is the function or loop a recur jumps to, along with the
+// number of frames entered before its body.
+type recurTarget struct {
+	loop  int
+	depth int
+}
+
+// compiler compiles analyzed forms to code for the VM. Locals stay in the
+// slots of frames as the analyzer assigned them, so the special forms the
+// VM has no instructions for are left to the tree walker.
+type compiler struct {
+	ch      *chunk
+	depth   int
+	targets []recurTarget
+}
+
+// compile compiles a top-level analyzed form.
+func compile(form Sexpr) *chunk {
+	c := &compiler{ch: &chunk{}}
+	c.compile(form, false)
+	c.emit(opReturn)
+	return c.ch
+}
+
+// compiled returns the code of the arity, compiling it the first time.
+func (a *arity) compiled() *chunk {
+	if a.code == nil {
+		c := &compiler{ch: &chunk{arity: a}, targets: []recurTarget{{loop: -1}}}
+		c.body(a.body, true)
+		c.emit(opReturn)
+		a.code = c.ch
+	}
+	return a.code
+}
+
+// emit appends an instruction and returns its position.
+func (c *compiler) emit(op opcode, operands ...int) int {
+	pos := len(c.ch.code)
+	c.ch.code = append(c.ch.code, byte(op))
+	for _, o := range operands {
+		if o < 0 || o > 0xffff {
+			throwf("Form is too large to compile")
+		}
+		c.ch.code = append(c.ch.code, byte(o>>8), byte(o))
+	}
+	return pos
+}
+
+// patch makes the jump at pos jump to the end of the code.
+func (c *compiler) patch(pos int) {
+	target := len(c.ch.code)
+	if target > 0xffff {
+		throwf("Form is too large to compile")
+	}
+	c.ch.code[pos+1], c.ch.code[pos+2] = byte(target>>8), byte(target)
+}
+
+func (c *compiler) constant(val Sexpr) {
+	c.ch.consts = append(c.ch.consts, val)
+	c.emit(opConst, len(c.ch.consts)-1)
+}
+
+// fallback leaves form to the tree walker.
+func (c *compiler) fallback(form Sexpr) {
+	c.ch.consts = append(c.ch.consts, form)
+	c.emit(opEval, len(c.ch.consts)-1)
+}
+
+func (c *compiler) bind(b binding) {
+	c.ch.bindings = append(c.ch.bindings, b)
+	c.emit(opBind, len(c.ch.bindings)-1)
+}
+
+// compile compiles form. tail tells whether form is in tail position of the
+// function being compiled.
+func (c *compiler) compile(form Sexpr, tail bool) {
+	switch t := form.(type) {
+	case *local:
+		c.emit(opLocal, t.depth, t.index)
+	case *Var:
+		c.ch.vars = append(c.ch.vars, t)
+		c.emit(opVar, len(c.ch.vars)-1)
+	case *constant:
+		c.constant(t.val)
+	case *defNode:
+		c.compile(t.val, false)
+		c.ch.vars = append(c.ch.vars, t.v)
+		c.emit(opDef, len(c.ch.vars)-1)
+	case *fnNode:
+		c.ch.fns = append(c.ch.fns, t)
+		c.emit(opClosure, len(c.ch.fns)-1)
+	case *letNode:
+		c.enter(t.size)
+		for i, b := range t.bindings {
+			c.compile(t.inits[i], false)
+			c.bind(b)
+		}
+		c.body(t.body, tail)
+		c.leave()
+	case *letfnNode:
+		c.enter(t.size)
+		for i, f := range t.fns {
+			c.compile(f, false)
+			c.bind(slotBinding(i))
+		}
+		c.body(t.body, tail)
+		c.leave()
+	case *loopNode:
+		c.enter(t.size)
+		for i, b := range t.bindings {
+			c.compile(t.inits[i], false)
+			c.bind(b)
+		}
+		c.ch.loops = append(c.ch.loops, loopInfo{node: t, start: len(c.ch.code)})
+		c.targets = append(c.targets, recurTarget{loop: len(c.ch.loops) - 1, depth: c.depth})
+		c.body(t.body, tail)
+		c.targets = c.targets[:len(c.targets)-1]
+		c.leave()
+	case *Vector:
+		items := t.Slice()
+		for _, item := range items {
+			c.compile(item, false)
+		}
+		c.emit(opVector, len(items))
+	case *HashMap:
+		t.Each(func(key, val Sexpr) {
+			c.compile(key, false)
+			c.compile(val, false)
+		})
+		c.emit(opMap, t.Length())
+	case *Expression:
+		c.call(t, tail)
+	case Number, Float, BigInt, Ratio, String, Boolean, *Keyword, nilValue:
+		c.constant(form)
+	default:
+		c.fallback(form)
+	}
+}
+
+func (c *compiler) enter(size int) {
+	c.emit(opEnter, size)
+	c.depth++
+}
+
+func (c *compiler) leave() {
+	c.emit(opLeave)
+	c.depth--
+}
+
+// body compiles the forms of a body, keeping the value of the last one.
+func (c *compiler) body(forms []Sexpr, tail bool) {
+	if len(forms) == 0 {
+		c.constant(Nil)
+		return
+	}
+	for i, form := range forms {
+		if i > 0 {
+			c.emit(opPop)
+		}
+		c.compile(form, tail && i == len(forms)-1)
+	}
+}
+
+// call compiles a call. The special forms bound in the root context get
+// instructions of their own; other macros are left to the tree walker.
+func (c *compiler) call(e *Expression, tail bool) {
+	if len(e.Elements) == 0 {
+		c.fallback(e)
+		return
+	}
+	args := e.Elements[1:]
+	if v, ok := e.Elements[0].(*Var); ok {
+		if _, ok := v.value.(Macros); ok {
+			switch {
+			case v.name == "if" && len(args) >= 2 && len(args) <= 3:
+				c.compile(args[0], false)
+				jump := c.emit(opJumpFalse, 0)
+				c.compile(args[1], tail)
+				end := c.emit(opJump, 0)
+				c.patch(jump)
+				if len(args) == 3 {
+					c.compile(args[2], tail)
+				} else {
+					c.constant(Nil)
+				}
+				c.patch(end)
+			case v.name == "do":
+				c.body(args, tail)
+			case v.name == "and" || v.name == "or":
+				c.logical(v.name, args, tail)
+			case v.name == "recur":
+				c.recur(args)
+			default:
+				c.fallback(e)
+			}
+			return
+		}
+	}
+
+	for _, form := range e.Elements {
+		c.compile(form, false)
+	}
+	c.ch.sites = append(c.ch.sites, callSite{head: e.Elements[0], name: frameName(e.Elements[0], nil), pos: e.Pos})
+	op := opCall
+	if tail {
+		op = opTailCall
+	}
+	c.emit(op, len(args), len(c.ch.sites)-1)
+}
+
+// logical compiles and and or, which stop at the first false or true value.
+func (c *compiler) logical(name Literal, args []Sexpr, tail bool) {
+	op := opAnd
+	if name == "or" {
+		op = opOr
+	}
+	if len(args) == 0 {
+		if op == opAnd {
+			c.constant(True)
+		} else {
+			c.constant(Nil)
+		}
+		return
+	}
+	var jumps []int
+	for i, arg := range args {
+		last := i == len(args)-1
+		c.compile(arg, tail && last)
+		if !last {
+			jumps = append(jumps, c.emit(op, 0))
+		}
+	}
+	for _, jump := range jumps {
+		c.patch(jump)
+	}
+}
+
+// recur compiles a recur to the innermost function or loop, which the
+// analyzer has checked it is in tail position of.
+func (c *compiler) recur(args []Sexpr) {
+	for _, arg := range args {
+		c.compile(arg, false)
+	}
+	target := c.targets[len(c.targets)-1]
+	if target.loop < 0 {
+		c.emit(opRecur, len(args))
+		return
+	}
+	c.emit(opLoop, target.loop, c.depth-target.depth)
+}
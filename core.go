@@ -1,9 +1,10 @@
-package main
+package clojura
 
 import (
 	"errors"
 	"fmt"
-	"math/rand"
+	"math"
+	"strings"
 	"time"
 )
 
@@ -12,22 +13,38 @@ var (
 	False = Boolean(false)
 )
 
+// Context is a frame of the running program. The root context has no
+// locals, globals live in the namespaces of the interpreter; every other
+// frame holds the locals of a function call, a let or a loop in slots
+// assigned by the analyzer.
 type Context struct {
-	vars   map[Literal]Sexpr
+	slots  []Sexpr
 	parent *Context
+	interp *Interpreter
 }
 
+// NewContext creates a root context when parent is nil and an empty frame
+// below parent otherwise.
 func NewContext(parent *Context) *Context {
+	if parent == nil {
+		return &Context{}
+	}
+	return newFrame(parent, 0)
+}
+
+// newFrame creates a frame with size slots below parent.
+func newFrame(parent *Context, size int) *Context {
 	return &Context{
-		vars:   make(map[Literal]Sexpr),
+		slots:  make([]Sexpr, size),
 		parent: parent,
+		interp: parent.interp,
 	}
 }
 
 func (c *Context) String() string {
 	s := ""
-	for k, v := range c.vars {
-		s += fmt.Sprintf("%s: %s\n", k, v)
+	for i, v := range c.slots {
+		s += fmt.Sprintf("%d: %s\n", i, v)
 	}
 	if c.parent != nil {
 		s += c.parent.String()
@@ -35,30 +52,22 @@ func (c *Context) String() string {
 	return s
 }
 
+// Get returns the value of the global key refers to in the current
+// namespace.
 func (c *Context) Get(key Literal) (Sexpr, bool) {
-	val, ok := c.vars[key]
-	if !ok && c.parent != nil {
-		return c.parent.Get(key)
+	if c.interp == nil {
+		return nil, false
+	}
+	v, _ := c.interp.lookupVar(key)
+	if v == nil || v.value == nil {
+		return nil, false
 	}
-	return val, ok
+	return v.value, true
 }
 
+// Set binds a global in the current namespace.
 func (c *Context) Set(key Literal, s Sexpr) {
-	c.vars[key] = s
-}
-
-func (c *Context) SetExclm(key Literal, s Sexpr) {
-	for c != nil {
-		_, ok := c.vars[key]
-		if ok {
-			break
-		}
-		c = c.parent
-	}
-
-	if c != nil {
-		c.vars[key] = s
-	}
+	c.interp.ns.intern(key).value = s
 }
 
 type Function interface {
@@ -69,67 +78,196 @@ type Macros interface {
 	Create(*Context, []Sexpr) Sexpr
 }
 
+// arity is one body of a function along with the binding forms of its
+// parameters. A variadic arity binds the arguments left after params to rest.
+// The body runs in a frame of size slots, the first of which holds the
+// function itself when it is named. code is the body compiled for the VM,
+// filled in the first time the VM calls the arity.
+type arity struct {
+	params []binding
+	rest   binding
+	body   []Sexpr
+	size   int
+	self   bool
+	sig    string
+	code   *chunk
+}
+
+func (a *arity) variadic() bool {
+	return a.rest != nil
+}
+
+// accepts reports whether the arity can be called with n arguments.
+func (a *arity) accepts(n int) bool {
+	if a.variadic() {
+		return n >= len(a.params)
+	}
+	return n == len(a.params)
+}
+
+// bind binds args to the parameters in c. Arguments passed by recur to a
+// variadic arity already hold the rest parameter as a sequence.
+func (a *arity) bind(c *Context, args []Sexpr, recur bool) {
+	for i, p := range a.params {
+		p.bind(c, args[i])
+	}
+	if !a.variadic() {
+		return
+	}
+	if recur {
+		a.rest.bind(c, args[len(a.params)])
+	} else if len(args) > len(a.params) {
+		a.rest.bind(c, listOf(args[len(a.params):]))
+	} else {
+		a.rest.bind(c, Nil)
+	}
+}
+
+func (a *arity) String() string {
+	return a.sig
+}
+
 type function struct {
 	name    string
-	args    []Literal
-	body    []Sexpr
+	arities []*arity
 	context *Context
 }
 
+// NewFunction creates a function with a single arity, as if written
+// (fn name [args] body*). A parameter following & is bound to the rest of
+// the arguments. The body can only refer to globals.
 func NewFunction(name string, args []Literal, body []Sexpr, c *Context) *function {
-	return &function{
-		name:    name,
-		args:    args,
-		body:    body,
-		context: c,
+	params := make([]Sexpr, len(args))
+	for i, arg := range args {
+		params[i] = arg
+	}
+	form := &Expression{Elements: []Sexpr{Literal("fn")}}
+	if name != "" {
+		form.Elements = append(form.Elements, Literal(name))
 	}
+	form.Elements = append(form.Elements, NewVector(params...))
+	form.Elements = append(form.Elements, body...)
+	return c.interp.analyze(form).Eval(c).(*function)
 }
 
-func (f function) Call(args []Sexpr) Sexpr {
-	context := NewContext(f.context)
-
-	var res Sexpr
-	for {
-		if len(args) != len(f.args) {
-			fmt.Println("Invalid number of arguments for", f.name)
-			return nil
-		}
-		for i, arg := range args {
-			context.Set(f.args[i], arg)
+// arityFor returns the arity to call with n arguments, preferring fixed
+// arities over the variadic one.
+func (f *function) arityFor(n int) *arity {
+	var res *arity
+	for _, a := range f.arities {
+		if !a.accepts(n) {
+			continue
 		}
-
-		for _, ex := range f.body {
-			res = ex.Eval(context)
+		if !a.variadic() {
+			return a
 		}
-		if res != nil && res.Type() == TypeRecur {
-			args = res.(*Recur).Args
-			continue
+		res = a
+	}
+	if res == nil {
+		accepted := make([]string, len(f.arities))
+		for i, a := range f.arities {
+			accepted[i] = a.String()
 		}
-		break
+		throwf("Wrong number of args (%d) passed to %s, accepts %s",
+			n, f.displayName(), strings.Join(accepted, " "))
 	}
 	return res
 }
 
-func (f function) String() string {
-	return "func " + f.name
+func (f *function) displayName() string {
+	if f.name == "" {
+		return "fn"
+	}
+	return f.name
+}
+
+// enter creates the frame arity a of the function runs in, holding args.
+func (f *function) enter(a *arity, args []Sexpr, recur bool) *Context {
+	frame := newFrame(f.context, a.size)
+	if a.self {
+		frame.slots[0] = f
+	}
+	a.bind(frame, args, recur)
+	return frame
 }
 
-func (f function) Type() CoreType {
+// Call runs the function. Calls in tail position of the body are made in
+// the same loop as recur, so that they don't grow the Go stack.
+func (f *function) Call(args []Sexpr) Sexpr {
+	in := f.context.interp
+	if in.vm {
+		return f.run(args)
+	}
+	fn := f
+	a := fn.arityFor(len(args))
+	pushed := false
+
+	recur := false
+	for {
+		res := evalBody(fn.enter(a, args, recur), a.body)
+	resolve:
+		for {
+			switch t := res.(type) {
+			case *tailForm:
+				res = evalTail(t.context, t.form)
+			case *tailCall:
+				g, ok := t.fn.(*function)
+				if !ok {
+					res = t.call()
+					continue
+				}
+				// The called function replaces this one on the stack.
+				if pushed {
+					in.stack[len(in.stack)-1] = t.frame
+				} else {
+					in.pushFrame(t.frame)
+					pushed = true
+				}
+				fn, args, recur = g, t.args, false
+				a = fn.arityFor(len(args))
+				break resolve
+			case *Recur:
+				args, recur = t.Args, true
+				break resolve
+			default:
+				if pushed {
+					in.popFrame()
+				}
+				return res
+			}
+		}
+	}
+}
+
+func (f *function) String() string {
+	return "func " + f.displayName()
+}
+
+func (f *function) Type() CoreType {
 	return TypeFunction
 }
 
-func (f function) Append(s Sexpr) error {
+func (f *function) Append(s Sexpr) error {
 	return errors.New("cannot append")
 }
 
-func (f function) Eval(c *Context) Sexpr {
+func (f *function) Eval(c *Context) Sexpr {
 	return f
 }
 
-func (f function) Bool() bool {
+func (f *function) Bool() bool {
 	return true
 }
 
+// call calls f, which should be a function, with args.
+func call(f Sexpr, args []Sexpr) Sexpr {
+	fun, ok := f.(Function)
+	if !ok {
+		throwf("%s is not a function", f)
+	}
+	return fun.Call(args)
+}
+
 type coreF func([]Sexpr) Sexpr
 
 func (cf coreF) Bool() bool {
@@ -181,201 +319,238 @@ func (m macros) Create(c *Context, args []Sexpr) Sexpr {
 	return m(c, args)
 }
 
-var coreContext *Context
-
-func init() {
-	rand.Seed(time.Now().UnixNano())
-
-	coreContext = NewContext(nil)
-	coreContext.Set("true", True)
-	coreContext.Set("false", False)
-	coreContext.Set("+", coreF(coreAdd))
-	coreContext.Set("-", coreF(coreSub))
-	coreContext.Set("def", macros(coreDef))
-	coreContext.Set("let", macros(coreLet))
-	// coreContext.Set("set!", macros(coreSetExclm))
-	coreContext.Set("println", coreF(corePrintln))
-	coreContext.Set("fn", macros(coreFn))
-	coreContext.Set("not", coreF(coreNot))
-	coreContext.Set("=", coreF(coreEq))
-	coreContext.Set("eq", coreF(coreEq))
-	coreContext.Set("if", macros(ifMacro))
-	coreContext.Set("head", coreF(coreHead))
-	coreContext.Set("tail", coreF(coreTail))
-	coreContext.Set("conj", coreF(coreConj))
-	coreContext.Set("do", macros(doMacro))
-	coreContext.Set("time", macros(coreTime))
-	coreContext.Set("cons", coreF(coreCons))
-	coreContext.Set("recur", coreF(coreRecur))
-	coreContext.Set("range", coreF(coreRange))
-	coreContext.Set("odd?", coreF(coreOdd))
-	coreContext.Set("load", coreF(coreLoad))
-	coreContext.Set(">", coreF(coreGreat))
-	coreContext.Set("<", coreF(coreLess))
-	coreContext.Set("<=", coreF(coreLessEq))
-	coreContext.Set(">=", coreF(coreGreatEq))
-	coreContext.Set("and", macros(coreAnd))
-	coreContext.Set("or", macros(coreOr))
-	coreContext.Set("random", coreF(coreRandom))
-}
-
-func coreAdd(args []Sexpr) Sexpr {
-	var res Number
-	for _, arg := range args {
-		n, ok := arg.(Number)
-		if ok {
-			res += n
-		}
+func newCoreContext(in *Interpreter) *Context {
+	c := NewContext(nil)
+	c.interp = in
+	c.Set("nil", Nil)
+	c.Set("true", True)
+	c.Set("false", False)
+	c.Set("+", coreF(coreAdd))
+	c.Set("-", coreF(coreSub))
+	c.Set("*", coreF(coreMul))
+	c.Set("/", coreF(coreDiv))
+	c.Set("+'", coreF(coreAddPromote))
+	c.Set("-'", coreF(coreSubPromote))
+	c.Set("*'", coreF(coreMulPromote))
+	c.Set("==", coreF(coreNumEq))
+	c.Set("number?", coreF(coreIsNumber))
+	c.Set("integer?", coreF(coreIsInteger))
+	c.Set("float?", coreF(coreIsFloat))
+	c.Set("ratio?", coreF(coreIsRatio))
+	c.Set("double", coreF(coreDouble))
+	c.Set("bigint", coreF(coreBigint))
+	c.Set("macroexpand-1", macros(macroexpand1Macro))
+	c.Set("macroexpand", macros(macroexpandMacro))
+	c.Set("macroexpand-all", macros(macroexpandAllMacro))
+	c.Set("gensym", coreF(coreGensym))
+	c.Set("print", coreF(in.corePrint))
+	c.Set("println", coreF(in.corePrintln))
+	c.Set("pr", coreF(in.corePr))
+	c.Set("prn", coreF(in.corePrn))
+	c.Set("str", coreF(coreStr))
+	c.Set("not", coreF(coreNot))
+	c.Set("=", coreF(coreEq))
+	c.Set("eq", coreF(coreEq))
+	c.Set("if", macros(ifMacro))
+	c.Set("head", coreF(coreFirst))
+	c.Set("tail", coreF(coreRest))
+	c.Set("conj", coreF(coreConj))
+	c.Set("vector", coreF(coreVector))
+	c.Set("vec", coreF(coreVec))
+	c.Set("vector?", coreF(coreIsVector))
+	c.Set("nth", coreF(coreNth))
+	c.Set("keyword", coreF(coreKeyword))
+	c.Set("keyword?", coreF(coreIsKeyword))
+	c.Set("name", coreF(coreName))
+	c.Set("hash-map", coreF(coreHashMap))
+	c.Set("map?", coreF(coreIsMap))
+	c.Set("get", coreF(coreGet))
+	c.Set("assoc", coreF(coreAssoc))
+	c.Set("dissoc", coreF(coreDissoc))
+	c.Set("contains?", coreF(coreContains))
+	c.Set("keys", coreF(coreKeys))
+	c.Set("vals", coreF(coreVals))
+	c.Set("merge", coreF(coreMerge))
+	c.Set("update", coreF(coreUpdate))
+	c.Set("get-in", coreF(coreGetIn))
+	c.Set("assoc-in", coreF(coreAssocIn))
+	c.Set("update-in", coreF(coreUpdateIn))
+	c.Set("subvec", coreF(coreSubvec))
+	c.Set("peek", coreF(corePeek))
+	c.Set("pop", coreF(corePop))
+	c.Set("do", macros(doMacro))
+	c.Set("time", macros(in.coreTime))
+	c.Set("cons", coreF(coreCons))
+	c.Set("recur", macros(recurMacro))
+	c.Set("range", coreF(coreRange))
+	c.Set("lazy-seq*", coreF(coreLazySeq))
+	c.Set("take", coreF(coreTake))
+	c.Set("drop", coreF(coreDrop))
+	c.Set("take-while", coreF(coreTakeWhile))
+	c.Set("drop-while", coreF(coreDropWhile))
+	c.Set("iterate", coreF(coreIterate))
+	c.Set("repeat", coreF(coreRepeat))
+	c.Set("cycle", coreF(coreCycle))
+	c.Set("odd?", coreF(coreOdd))
+	c.Set("load", coreF(in.coreLoad))
+	c.Set(">", coreF(coreGreat))
+	c.Set("<", coreF(coreLess))
+	c.Set("<=", coreF(coreLessEq))
+	c.Set(">=", coreF(coreGreatEq))
+	c.Set("and", macros(coreAnd))
+	c.Set("nil?", coreF(coreIsNil))
+	c.Set("some?", coreF(coreIsSome))
+	c.Set("fnil", coreF(coreFnil))
+	c.Set("or", macros(coreOr))
+	c.Set("random", coreF(in.coreRandom))
+	c.Set("throw", coreF(coreThrow))
+	c.Set("ex-info", coreF(coreExInfo))
+	c.Set("ex-data", coreF(coreExData))
+	c.Set("ex-message", coreF(coreExMessage))
+	c.Set("ex-cause", coreF(coreExCause))
+	c.Set("trampoline", coreF(coreTrampoline))
+	c.Set("hash-set", coreF(coreHashSet))
+	c.Set("set", coreF(coreSet))
+	c.Set("set?", coreF(coreIsSet))
+	c.Set("disj", coreF(coreDisj))
+	c.Set("seq", coreF(coreSeq))
+	c.Set("first", coreF(coreFirst))
+	c.Set("rest", coreF(coreRest))
+	c.Set("next", coreF(coreNext))
+	c.Set("count", coreF(coreCount))
+	c.Set("list", coreF(coreList))
+	c.Set("list?", coreF(coreIsList))
+	c.Set("string?", coreF(coreIsString))
+	c.Set("require", coreF(in.coreRequire))
+	c.Set("in-ns", coreF(in.coreInNs))
+	c.Set("ns-publics", coreF(in.coreNsPublics))
+	c.Set("ns-map", coreF(in.coreNsMap))
+	return c
+}
+
+// evalBody evaluates all but the last form of a body and leaves the last
+// one, which is in tail position, to the caller.
+func evalBody(c *Context, body []Sexpr) Sexpr {
+	if len(body) == 0 {
+		return Nil
+	}
+	for _, ex := range body[:len(body)-1] {
+		ex.Eval(c)
+	}
+	return inTail(c, body[len(body)-1])
+}
+
+// bindingArgs checks the (name value) arguments of def-like macros.
+func bindingArgs(name string, args []Sexpr) (Literal, Sexpr) {
+	if len(args) != 3 {
+		throwf("Wrong number of args (%d) passed to %s", len(args)-1, name)
+	}
+	n, ok := args[1].(Literal)
+	if !ok {
+		throwf("%s name should be a literal, got %s", name, args[1])
 	}
-
-	return Number(res)
+	return n, args[2]
 }
 
-func coreSub(args []Sexpr) Sexpr {
+func coreEq(args []Sexpr) Sexpr {
 	if len(args) < 1 {
-		return Number(0)
-	}
-
-	res, _ := args[0].(Number)
-	for _, arg := range args[1:] {
-		r, ok := arg.(Number)
-		if ok {
-			res -= r
-		}
+		throwf("Wrong number of args (0) passed to =")
 	}
 
-	return res
-
-}
-
-func coreDef(c *Context, args []Sexpr) Sexpr {
-	args = args[1:]
-	var res Sexpr
-	if len(args) > 1 {
-		n, ok := args[0].(Literal)
-		if ok {
-			res = args[1].Eval(c)
-			coreContext.Set(n, res)
+	for _, b := range args[1:] {
+		if !equal(args[0], b) {
+			return False
 		}
 	}
-	return res
+	return True
 }
 
-func coreLet(c *Context, args []Sexpr) Sexpr {
-	args = args[1:]
-	var res Sexpr
-	if len(args) > 1 {
-		n, ok := args[0].(Literal)
-		if ok {
-			res = args[1].Eval(c)
-			c.Set(n, res)
-		}
+func equal(a, b Sexpr) bool {
+	if isNumeric(a) && isNumeric(b) {
+		return numEqual(a, b)
 	}
-	return res
-}
-
-func coreSetExclm(c *Context, args []Sexpr) Sexpr {
-	args = args[1:]
-	var res Sexpr
-	if len(args) > 1 {
-		n, ok := args[0].(Literal)
-		if ok {
-			res = args[1].Eval(c)
-			c.SetExclm(n, res)
-		}
+	// Lists, vectors and sequences are equal when their elements are.
+	if isSequential(a) && isSequential(b) {
+		return sequentialEqual(a, b)
 	}
-	return res
-}
-
-func coreEq(args []Sexpr) Sexpr {
-	if len(args) < 1 {
-		return True
+	if a.Type() != b.Type() {
+		return false
 	}
-
-	if len(args) > 2 {
-		return False
+	if ma, ok := a.(*HashMap); ok {
+		mb, ok := b.(*HashMap)
+		return ok && mapsEqual(ma, mb)
 	}
-
-	a, b := args[0], args[1]
-
-	if a.Type() != b.Type() {
-		return False
+	if sa, ok := a.(*Set); ok {
+		sb, ok := b.(*Set)
+		return ok && setsEqual(sa, sb)
 	}
-
 	switch a.Type() {
-	case TypeNumber:
-		if a.(Number) == b.(Number) {
-			return True
-		}
-		return False
+	case TypeNil:
+		return true
 	case TypeBoolean:
-		if a.Bool() == b.Bool() {
-			return True
-		}
-		return False
+		return a.Bool() == b.Bool()
+	case TypeString:
+		return a.(String) == b.(String)
+	case TypeLiteral:
+		return a.(Literal) == b.(Literal)
+	case TypeKeyword:
+		return a == b
 	case TypeMacros:
 		fallthrough
 	case TypeFunction:
-		if a == b {
-			return True
-		}
-		return False
+		return a == b
 	}
-	return False
+	return false
 }
 
-func coreNot(args []Sexpr) Sexpr {
-	if len(args) < 1 {
-		return True
+func isSequential(s Sexpr) bool {
+	switch s.(type) {
+	case *Vector, Seq:
+		return true
 	}
+	return false
+}
 
-	if len(args) > 2 {
-		return False
+// sequentialEqual compares lists, vectors and sequences element by element.
+func sequentialEqual(a, b Sexpr) bool {
+	as, bs := sequentialItems(a), sequentialItems(b)
+	if len(as) != len(bs) {
+		return false
 	}
-
-	if args[0].Bool() {
-		return False
+	for i := range as {
+		if !equal(as[i], bs[i]) {
+			return false
+		}
 	}
-	return True
+	return true
 }
 
-func corePrintln(args []Sexpr) Sexpr {
-	res := ""
-	for _, arg := range args {
-		if arg == nil {
-			res += "nil "
-		} else {
-			res += arg.String() + " "
-		}
+func sequentialItems(s Sexpr) []Sexpr {
+	switch t := s.(type) {
+	case *Vector:
+		return t.Slice()
+	case *List:
+		return t.Slice()
+	case Seq:
+		return seqItems(t)
 	}
-	fmt.Println(res)
 	return nil
 }
 
-func coreFn(c *Context, args []Sexpr) Sexpr {
-	if len(args) < 3 {
-		return nil
-	}
-
-	name := args[0].String()
-	argp, body := args[1], args[2:]
-	args = argp.(*Expression).Elements
-	largs := make([]Literal, len(args))
+func coreNot(args []Sexpr) Sexpr {
+	checkArity("not", args, 1)
 
-	for i, arg := range args {
-		a, ok := arg.(Literal)
-		if !ok {
-			fmt.Println("fn argument should be a literal")
-			return nil
-		}
-		largs[i] = a
+	if args[0].Bool() {
+		return False
 	}
-	return NewFunction(name, largs, body, c)
+	return True
 }
 
 func ifMacro(c *Context, args []Sexpr) Sexpr {
 	if len(args) < 3 {
-		return nil
+		throwf("Too few arguments to if")
+	} else if len(args) > 4 {
+		throwf("Too many arguments to if")
 	}
 
 	clause := args[1]
@@ -387,332 +562,159 @@ func ifMacro(c *Context, args []Sexpr) Sexpr {
 	}
 
 	res := clause.Eval(c)
-	if res != nil && res.Bool() {
-		return good.Eval(c)
+	if res.Bool() {
+		return inTail(c, good)
 	} else if bad != nil {
-		return bad.Eval(c)
-	}
-	return nil
-}
-
-func coreHead(args []Sexpr) Sexpr {
-	if len(args) != 1 {
-		log.Error("bad num of args for head")
-		return nil
-	}
-	l := args[0]
-
-	lst, ok := l.(*List)
-	if !ok {
-		log.Error("head arg shold be list, got", l.Type())
-		return nil
+		return inTail(c, bad)
 	}
-
-	return lst.Head()
-}
-
-func coreTail(args []Sexpr) Sexpr {
-	if len(args) != 1 {
-		log.Error("bad num of args for tail")
-		return nil
-	}
-	l := args[0]
-
-	lst, ok := l.(*List)
-	if !ok {
-		log.Error("head arg shold be list, got", l.Type())
-		return nil
-	}
-
-	return lst.GetTail()
+	return Nil
 }
 
+// coreConj adds elements to a collection where it is cheapest: at the end
+// of a vector and in front of a list or a sequence. nil is taken as the
+// empty list.
 func coreConj(args []Sexpr) Sexpr {
-	if len(args) != 2 {
-		log.Error("bad num of args for conj")
-		return nil
-	}
-	l1 := args[0]
-	l2 := args[1]
-	if l1 == nil && l2 == nil {
-		log.Error("conj args shold be list, got", nil, nil)
-		return nil
+	if len(args) == 0 {
+		return NewVector()
 	}
-
-	if l1 == nil {
-		if l, ok := l2.(*List); ok {
-			return l.Copy()
+	switch t := args[0].(type) {
+	case *Vector:
+		for _, arg := range args[1:] {
+			t = t.Conj(arg)
+		}
+		return t
+	case *Set:
+		for _, arg := range args[1:] {
+			t = t.Conj(arg)
+		}
+		return t
+	case *HashMap:
+		for _, arg := range args[1:] {
+			entry, ok := arg.(*Vector)
+			if !ok || entry.Length() != 2 {
+				throwf("conj on a map takes [key value] vectors, got %s", arg)
+			}
+			key, _ := entry.Nth(0)
+			val, _ := entry.Nth(1)
+			t = t.Assoc(key, val)
 		}
-	} else if l2 == nil {
-		if l, ok := l1.(*List); ok {
-			return l.Copy()
+		return t
+	case nilValue:
+		return NewList().conjAll(args[1:])
+	case *List:
+		return t.conjAll(args[1:])
+	case Seq:
+		var res Seq = t
+		for _, arg := range args[1:] {
+			res = &Cons{arg, res}
 		}
+		return res
 	}
+	throwf("conj not supported on %s", args[0])
+	return nil
+}
 
-	lst1, ok1 := l1.(*List)
-	lst2, ok2 := l2.(*List)
-	if !ok1 || !ok2 {
-		log.Error("conj args shold be list, got", l1.Type(), l2.Type())
-		return nil
-	}
+func coreList(args []Sexpr) Sexpr {
+	return listOf(args)
+}
 
-	return lst2.Conj(lst1)
+func coreIsList(args []Sexpr) Sexpr {
+	checkArity("list?", args, 1)
+	_, ok := args[0].(*List)
+	return Boolean(ok)
 }
 
 func doMacro(c *Context, args []Sexpr) Sexpr {
-	if len(args) < 2 {
-		return nil
-	}
-
-	var res Sexpr
-	for _, arg := range args[1:] {
-		res = arg.Eval(c)
-	}
-	return res
+	return evalBody(c, args[1:])
 }
 
-func coreTime(c *Context, args []Sexpr) Sexpr {
-	if len(args) != 2 {
-		return nil
-	}
+func (in *Interpreter) coreTime(c *Context, args []Sexpr) Sexpr {
+	checkArity("time", args[1:], 1)
 
 	start := time.Now()
 	res := args[1].Eval(c)
-	fmt.Printf("Executed %s in %s\n", args[1], time.Since(start))
+	fmt.Fprintf(in.out, "Executed %s in %s\n", args[1], time.Since(start))
 	return res
 }
 
-func coreRecur(args []Sexpr) Sexpr {
-	return &Recur{args}
-}
-
-func coreCons(args []Sexpr) Sexpr {
-	if len(args) != 2 {
-		log.Error("bad num of args for cons")
-		return nil
-	}
-	v := args[0]
-	l := args[1]
-	if l == nil {
-		log.Error("cons first arg shold be list, got", nil)
-		return nil
-	}
-
-	lst, ok := l.(*List)
-	if !ok {
-		log.Error("cons first arg shold be list, got", l.Type())
-		return nil
-	}
-
-	return lst.Add(v)
-}
-
-func coreRange(args []Sexpr) Sexpr {
-	if len(args) != 1 {
-		log.Error("bad num of args for range")
-		return nil
-	}
-	n, ok := args[0].(Number)
+// numberArg raises an exception unless s is a number.
+func numberArg(name string, s Sexpr) Number {
+	n, ok := s.(Number)
 	if !ok {
-		log.Error("range first arg shold be number, got", args[0].Type())
-		return nil
-	}
-
-	res := NewList()
-	var i Number
-	for i = n - 1; i >= 0; i-- {
-		res.addFast(i)
+		throwf("%s argument should be a number, got %s", name, s)
 	}
-
-	return res
+	return n
 }
 
 func coreOdd(args []Sexpr) Sexpr {
-	if len(args) != 1 {
-		log.Error("bad num of args for odd?")
-		return nil
-	}
-	n, ok := args[0].(Number)
-	if !ok {
-		log.Error("odd? first arg shold be number, got", args[0].Type())
-		return nil
+	checkArity("odd?", args, 1)
+	if n, ok := args[0].(BigInt); ok {
+		return Boolean(n.val.Bit(0) != 0)
 	}
+	n := numberArg("odd?", args[0])
 
 	return Boolean((n % 2) != 0)
 }
 
-func coreLoad(args []Sexpr) Sexpr {
-	if len(args) != 1 {
-		log.Error("bad num of args for load")
-		return nil
-	}
-	n, ok := args[0].(Literal)
-	if !ok {
-		log.Error("load first arg shold be literal, got", args[0].Type())
-		return nil
-	}
-
-	err := LoadFile(string(n))
-	if err != nil {
-		log.Errorf("Failed to load file '%s': %v\n", n, err)
-	}
-	return nil
-}
-
-func coreGreat(args []Sexpr) Sexpr {
-	if len(args) < 1 {
-		return False
-	}
-
-	a1 := args[0]
-	switch t := a1.(type) {
-	case Number:
-		for _, el := range args[1:] {
-			v, ok := el.(Number)
-			if !ok {
-				return False
-			}
-			if t <= v {
-				return False
-			}
-		}
-		return True
-	}
-	return False
-}
-func coreLess(args []Sexpr) Sexpr {
-	if len(args) < 1 {
-		return False
-	}
-
-	a1 := args[0]
-	switch t := a1.(type) {
-	case Number:
-		for _, el := range args[1:] {
-			v, ok := el.(Number)
-			if !ok {
-				return False
-			}
-			if t >= v {
-				return False
-			}
-		}
-		return True
-	}
-	return False
-}
-func coreLessEq(args []Sexpr) Sexpr {
-	if len(args) < 1 {
-		return False
-	}
-
-	a1 := args[0]
-	switch t := a1.(type) {
-	case Number:
-		for _, el := range args[1:] {
-			v, ok := el.(Number)
-			if !ok {
-				return False
-			}
-			if t > v {
-				return False
-			}
-		}
-		return True
-	}
-	return False
-}
-
-func coreGreatEq(args []Sexpr) Sexpr {
-	if len(args) < 1 {
-		return False
-	}
-
-	a1 := args[0]
-	switch t := a1.(type) {
-	case Number:
-		for _, el := range args[1:] {
-			v, ok := el.(Number)
-			if !ok {
-				return False
-			}
-			if t < v {
-				return False
-			}
-		}
-		return True
-	}
-	return False
-}
-
+// coreAnd evaluates its forms until one is falsey. The last form is in tail
+// position.
 func coreAnd(c *Context, args []Sexpr) Sexpr {
-	if len(args) < 2 {
-		return False
+	if len(args) == 1 {
+		return True
 	}
-
-	var res Sexpr
-	for _, el := range args[1:] {
-		res = el.Eval(c)
-		if !res.Bool() {
-			break
+	for _, el := range args[1 : len(args)-1] {
+		if res := el.Eval(c); !res.Bool() {
+			return res
 		}
 	}
-	return res
+	return inTail(c, args[len(args)-1])
 }
 
+// coreOr evaluates its forms until one is truthy. The last form is in tail
+// position.
 func coreOr(c *Context, args []Sexpr) Sexpr {
-	if len(args) < 2 {
-		return False
+	if len(args) == 1 {
+		return Nil
 	}
-
-	var res Sexpr
-	for _, el := range args[1:] {
-		res = el.Eval(c)
-		if res.Bool() {
-			break
+	for _, el := range args[1 : len(args)-1] {
+		if res := el.Eval(c); res.Bool() {
+			return res
 		}
 	}
-	return res
+	return inTail(c, args[len(args)-1])
 }
 
-func coreRandom(args []Sexpr) Sexpr {
-	if len(args) > 2 {
-		fmt.Println("random accepts 0, 1 or 2 arguments")
-		return nil
-	}
-	if len(args) == 0 {
-		return Number(rand.Int())
-	}
-
-	if len(args) == 1 {
-		n, ok := args[0].(Number)
-		if !ok {
-			fmt.Println("random argument should be number")
-			return nil
-		}
-
-		return Number(rand.Intn(int(n)))
-	}
-
-	if len(args) == 2 {
-		n1, ok := args[0].(Number)
-		if !ok {
-			fmt.Println("random arguments should be number")
-			return nil
+func (in *Interpreter) coreRandom(args []Sexpr) Sexpr {
+	switch len(args) {
+	case 0:
+		return Number(in.rand.Int())
+	case 1:
+		n := numberArg("random", args[0])
+		if n <= 0 {
+			throwf("random argument should be positive, got %s", n)
 		}
-
-		n2, ok := args[1].(Number)
-		if !ok {
-			fmt.Println("random arguments should be number")
-			return nil
-		}
-
+		return Number(in.rand.Intn(int(n)))
+	case 2:
+		n1 := numberArg("random", args[0])
+		n2 := numberArg("random", args[1])
 		if n1 > n2 {
 			n2, n1 = n1, n2
 		}
-
-		r := rand.Intn(int(n2 - n1))
-		return Number(r) + n1
+		if n1 == n2 {
+			throwf("random range should not be empty")
+		}
+		// The width of the range may not fit in a Number, but it always
+		// fits in a uint64.
+		width := uint64(n2) - uint64(n1)
+		if width <= math.MaxInt64 {
+			return n1 + Number(in.rand.Int63n(int64(width)))
+		}
+		r := in.rand.Uint64()
+		for r >= width {
+			r = in.rand.Uint64()
+		}
+		return Number(uint64(n1) + r)
 	}
+	throwf("random accepts 0, 1 or 2 arguments")
 	return nil
 }
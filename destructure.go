@@ -0,0 +1,176 @@
+package clojura
+
+// binding is an analyzed binding form. It binds the names of the form to
+// the matching parts of a value by storing them in the slots of a frame.
+type binding interface {
+	bind(c *Context, val Sexpr)
+}
+
+// slotBinding binds a single name.
+type slotBinding int
+
+func (b slotBinding) bind(c *Context, val Sexpr) {
+	c.slots[b] = val
+}
+
+// seqBinding destructures a sequence: [a b & rest :as all].
+type seqBinding struct {
+	items    []binding
+	rest, as binding
+}
+
+func (b *seqBinding) bind(c *Context, val Sexpr) {
+	if val != Nil && !isSequential(val) {
+		throwf("Can't destructure %s as a sequence", val)
+	}
+	// Only the elements bound are realized, so infinite sequences can be
+	// destructured.
+	s := seqOf(val)
+	for _, item := range b.items {
+		res := Nil
+		if s != nil {
+			res = s.First()
+			s = s.Rest().Seq()
+		}
+		item.bind(c, res)
+	}
+	if b.rest != nil {
+		b.rest.bind(c, orNilSeq(s))
+	}
+	if b.as != nil {
+		b.as.bind(c, val)
+	}
+}
+
+// mapBinding destructures a map: {:keys [x y] :or {y 0} :as m}.
+type mapBinding struct {
+	entries []mapEntry
+	as      binding
+}
+
+// mapEntry binds the value found under key, or the value of def when the
+// key is missing and a default is given.
+type mapEntry struct {
+	b   binding
+	key Sexpr
+	def Sexpr
+}
+
+func (b *mapBinding) bind(c *Context, val Sexpr) {
+	// Rest arguments destructured as a map hold key value pairs.
+	if isSequential(val) {
+		items := sequentialItems(val)
+		if len(items)%2 != 0 {
+			throwf("No value supplied for key: %s", items[len(items)-1])
+		}
+		val = NewHashMap(items...)
+	}
+	if val != Nil {
+		mapArg("destructure", val)
+	}
+	for _, e := range b.entries {
+		res, ok := get(val, e.key)
+		if !ok && e.def != nil {
+			res, ok = e.def.Eval(c), true
+		}
+		if !ok {
+			res = Nil
+		}
+		e.b.bind(c, res)
+	}
+	if b.as != nil {
+		b.as.bind(c, val)
+	}
+}
+
+// binding analyzes a binding form, adding the names it binds to the current
+// scope. A binding form is a symbol, a vector for sequential destructuring
+// or a map for associative destructuring. Defaults given with :or are
+// analyzed in the scope the form is bound in.
+func (a *analyzer) binding(form Sexpr) binding {
+	switch t := form.(type) {
+	case Literal:
+		return slotBinding(a.scope.add(t))
+	case *Vector:
+		return a.seqBinding(t.Slice())
+	case *HashMap:
+		return a.mapBinding(t)
+	}
+	a.errorf("Unsupported binding form: %s", form)
+	return nil
+}
+
+func (a *analyzer) seqBinding(forms []Sexpr) binding {
+	res := &seqBinding{}
+	for i := 0; i < len(forms); i++ {
+		switch forms[i] {
+		case Literal("&"):
+			if i+1 >= len(forms) {
+				a.errorf("& should be followed by a binding form")
+			}
+			i++
+			res.rest = a.binding(forms[i])
+		case Intern("as"):
+			if i+1 >= len(forms) {
+				a.errorf(":as should be followed by a name")
+			}
+			i++
+			res.as = a.binding(forms[i])
+		default:
+			res.items = append(res.items, a.binding(forms[i]))
+		}
+	}
+	return res
+}
+
+func (a *analyzer) mapBinding(form *HashMap) binding {
+	var defaults *HashMap
+	if or, ok := form.Get(Intern("or")); ok {
+		m, ok := or.(*HashMap)
+		if !ok {
+			a.errorf(":or argument should be a map, got %s", or)
+		}
+		defaults = m
+	}
+	res := &mapBinding{}
+	entry := func(name, key Sexpr) {
+		e := mapEntry{key: key}
+		if defaults != nil {
+			if d, ok := defaults.Get(name); ok {
+				e.def = a.analyze(d, false)
+			}
+		}
+		e.b = a.binding(name)
+		res.entries = append(res.entries, e)
+	}
+
+	form.Each(func(k, v Sexpr) {
+		switch k {
+		case Intern("or"):
+		case Intern("as"):
+			res.as = a.binding(v)
+		case Intern("keys"), Intern("strs"), Intern("syms"):
+			names, ok := v.(*Vector)
+			if !ok {
+				a.errorf("%s argument should be a vector, got %s", k, v)
+			}
+			for _, name := range names.Slice() {
+				n, ok := name.(Literal)
+				if !ok {
+					a.errorf("%s should hold names, got %s", k, name)
+				}
+				switch k {
+				case Intern("keys"):
+					entry(n, Intern(string(n)))
+				case Intern("strs"):
+					entry(n, String(n))
+				default:
+					entry(n, n)
+				}
+			}
+		default:
+			entry(k, formToData(v))
+		}
+	})
+	return res
+}
@@ -0,0 +1,26 @@
+package clojura
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDestructuring(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	tests := map[string]string{
+		"(let [[a b] [1 2]] (+ a b))":                              "3",
+		"(let [[a b & rest :as all] '(1 2 3 4)] [a b rest all])":   "[1 2 (3 4) (1 2 3 4)]",
+		"(let [[a b c] [1]] [a b c])":                              "[1 nil nil]",
+		"(let [[a [b c]] [1 [2 3]]] [a b c])":                      "[1 2 3]",
+		"(let [{:keys [x y] :or {y 0} :as m} {:x 1}] [x y m])":     "[1 0 {:x 1}]",
+		`(let [{:strs [s]} {"s" 1}] s)`:                            "1",
+		"(let [{a :a [b] :b} {:a 1 :b [2]}] [a b])":                "[1 2]",
+		"((fn [[a b] {:keys [c]}] [a b c]) [1 2] {:c 3})":          "[1 2 3]",
+		"((fn [a & {:keys [k]}] [a k]) 1 :k 2)":                    "[1 2]",
+		"(try (let [[a] 1] a) (catch Exception e (ex-message e)))": `"Can't destructure 1 as a sequence"`,
+	}
+	checkEval(t, in, tests)
+	if _, err := in.Eval("(fn [1] 1)"); err == nil || err.Error() != "NO_SOURCE_FILE:1:1: Unsupported binding form: 1" {
+		t.Errorf("expected an unsupported binding form error, got %v", err)
+	}
+}
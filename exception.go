@@ -0,0 +1,280 @@
+package clojura
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Exception is a clojura exception. Raising one unwinds evaluation (as a Go
+// panic) up to the nearest try form or, when uncaught, up to the Interpreter,
+// which returns it as a regular Go error.
+type Exception struct {
+	Message string
+	Data    Sexpr
+	Cause   *Exception
+	// Trace is the call stack at the moment the exception was raised,
+	// innermost call first. It is filled in once the exception is caught.
+	Trace []Frame
+}
+
+// Frame is a single function call on the interpreter's call stack: the name
+// of the called function and the place it was called from.
+type Frame struct {
+	Name string
+	Pos  Pos
+}
+
+func (f Frame) String() string {
+	return fmt.Sprintf("at %s (%s)", f.Name, f.Pos)
+}
+
+// maxDepth is the number of nested calls after which a StackOverflowError is
+// raised, well before the Go stack runs out.
+const maxDepth = 10000
+
+// pushFrame records a call on the interpreter's call stack.
+func (in *Interpreter) pushFrame(f Frame) {
+	if len(in.stack) >= maxDepth {
+		throwf("StackOverflowError")
+	}
+	in.stack = append(in.stack, f)
+}
+
+// popFrame removes the innermost call from the interpreter's call stack.
+func (in *Interpreter) popFrame() {
+	in.stack = in.stack[:len(in.stack)-1]
+}
+
+// NewException creates an exception with a formatted message.
+func NewException(format string, args ...interface{}) *Exception {
+	return &Exception{
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// throwf raises a new exception with a formatted message.
+func throwf(format string, args ...interface{}) {
+	panic(NewException(format, args...))
+}
+
+// checkArity raises an exception unless exactly n arguments were passed.
+func checkArity(name string, args []Sexpr, n int) {
+	if len(args) != n {
+		throwf("Wrong number of args (%d) passed to %s", len(args), name)
+	}
+}
+
+func (e *Exception) Error() string {
+	return e.Message
+}
+
+func (e *Exception) Type() CoreType {
+	return TypeException
+}
+
+func (e *Exception) Bool() bool {
+	return true
+}
+
+func (e *Exception) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (e *Exception) String() string {
+	if e.Data != nil && e.Data != Nil {
+		return fmt.Sprintf("#error {:message %q :data %s}", e.Message, e.Data)
+	}
+	return fmt.Sprintf("#error {:message %q}", e.Message)
+}
+
+func (e *Exception) Eval(c *Context) Sexpr {
+	return e
+}
+
+// PrintStackTrace writes the message of the exception, followed by its
+// stack trace and the ones of its causes, to w.
+func (e *Exception) PrintStackTrace(w io.Writer) {
+	for cause := e; cause != nil; cause = cause.Cause {
+		if cause == e {
+			fmt.Fprintf(w, "Exception: %s\n", cause.Message)
+		} else {
+			fmt.Fprintf(w, "Caused by: %s\n", cause.Message)
+		}
+		for _, frame := range cause.Trace {
+			fmt.Fprintf(w, "\t%s\n", frame)
+		}
+	}
+}
+
+// catch runs f and returns the exception it raised, if any, with its stack
+// trace recorded. Go errors raised by f, such as runtime errors, are turned
+// into exceptions; other panics are passed through.
+func (in *Interpreter) catch(f func()) (err *Exception) {
+	depth := len(in.stack)
+	defer func() {
+		if r := recover(); r != nil {
+			var e *Exception
+			switch t := r.(type) {
+			case *Exception:
+				e = t
+			case error:
+				e = NewException("%v", t)
+			default:
+				panic(r)
+			}
+			if e.Trace == nil {
+				e.Trace = make([]Frame, 0, len(in.stack)-depth)
+				for i := len(in.stack) - 1; i >= 0; i-- {
+					e.Trace = append(e.Trace, in.stack[i])
+				}
+			}
+			in.stack = in.stack[:depth]
+			err = e
+		}
+	}()
+	f()
+	return nil
+}
+
+func coreThrow(args []Sexpr) Sexpr {
+	checkArity("throw", args, 1)
+	e, ok := args[0].(*Exception)
+	if !ok {
+		throwf("throw argument should be an exception, got %s", args[0])
+	}
+	panic(e)
+}
+
+func coreExInfo(args []Sexpr) Sexpr {
+	if len(args) != 2 && len(args) != 3 {
+		throwf("Wrong number of args (%d) passed to ex-info", len(args))
+	}
+	e := &Exception{
+		Message: printString(args[0]),
+		Data:    args[1],
+	}
+	if len(args) == 3 {
+		cause, ok := args[2].(*Exception)
+		if !ok {
+			throwf("ex-info cause should be an exception, got %s", args[2])
+		}
+		e.Cause = cause
+	}
+	return e
+}
+
+func coreExData(args []Sexpr) Sexpr {
+	checkArity("ex-data", args, 1)
+	if e, ok := args[0].(*Exception); ok {
+		return orNil(e.Data)
+	}
+	return Nil
+}
+
+func coreExMessage(args []Sexpr) Sexpr {
+	checkArity("ex-message", args, 1)
+	if e, ok := args[0].(*Exception); ok {
+		return String(e.Message)
+	}
+	return Nil
+}
+
+func coreExCause(args []Sexpr) Sexpr {
+	checkArity("ex-cause", args, 1)
+	if e, ok := args[0].(*Exception); ok && e.Cause != nil {
+		return e.Cause
+	}
+	return Nil
+}
+
+// tryNode implements (try body* (catch Type e handler*)? (finally cleanup*)?).
+// All exceptions share one type, so a try accepts a single catch clause whose
+// class name is only kept for Clojure compatibility. The handler runs in a
+// frame holding the exception.
+type tryNode struct {
+	node
+	body     []Sexpr
+	catch    []Sexpr
+	hasCatch bool
+	finally  []Sexpr
+}
+
+func (a *analyzer) analyzeTry(e *Expression) Sexpr {
+	res := &tryNode{node: node{e}}
+	var body, catch, finally []Sexpr
+	for _, arg := range e.Elements[1:] {
+		switch clauseName(arg) {
+		case "catch":
+			if catch != nil {
+				a.errorf("try accepts only one catch clause")
+			}
+			catch = arg.(*Expression).Elements
+			if len(catch) < 3 {
+				a.errorf("catch should be (catch Type name body*)")
+			}
+			if _, ok := catch[2].(Literal); !ok {
+				a.errorf("catch binding should be a literal, got %s", catch[2])
+			}
+		case "finally":
+			finally = arg.(*Expression).Elements
+		default:
+			if catch != nil || finally != nil {
+				a.errorf("try body can't follow catch or finally")
+			}
+			body = append(body, arg)
+		}
+	}
+
+	res.body = a.analyzeAll(body)
+	if catch != nil {
+		a.push()
+		a.scope.add(catch[2].(Literal))
+		res.catch = a.analyzeAll(catch[3:])
+		res.hasCatch = true
+		a.pop()
+	}
+	if finally != nil {
+		res.finally = a.analyzeAll(finally[1:])
+	}
+	return res
+}
+
+func (t *tryNode) Eval(c *Context) Sexpr {
+	if len(t.finally) > 0 {
+		defer func() {
+			for _, ex := range t.finally {
+				ex.Eval(c)
+			}
+		}()
+	}
+
+	res := Nil
+	err := c.interp.catch(func() {
+		for _, ex := range t.body {
+			res = ex.Eval(c)
+		}
+	})
+	if err != nil {
+		if !t.hasCatch {
+			panic(err)
+		}
+		frame := newFrame(c, 1)
+		frame.slots[0] = err
+		res = Nil
+		for _, ex := range t.catch {
+			res = ex.Eval(frame)
+		}
+	}
+	return res
+}
+
+// clauseName returns the head symbol of a (catch ...) or (finally ...) form.
+func clauseName(s Sexpr) Literal {
+	e, ok := s.(*Expression)
+	if !ok || len(e.Elements) < 1 {
+		return ""
+	}
+	name, _ := e.Elements[0].(Literal)
+	return name
+}
@@ -0,0 +1,34 @@
+package clojura
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTryCatch(t *testing.T) {
+	var out bytes.Buffer
+	in := NewInterpreter(&out, &out)
+	checkEval(t, in, map[string]string{
+		`(try (throw (ex-info "boom" {:a 1})) (catch Exception e (ex-data e)))`: "{:a 1}",
+		`(try (+ 1 "a") (catch Exception e :caught))`:                           ":caught",
+		`(try 1 2)`: "2",
+		`(try (throw (ex-info "a" {})) (catch Exception e))`:                "nil",
+		`(ex-message (ex-cause (ex-info "outer" {} (ex-info "inner" {}))))`: `"inner"`,
+		`[(ex-message 1) (ex-data 1) (ex-cause (ex-info "a" {}))]`:          "[nil nil nil]",
+		`(try (try (throw (ex-info "in" {})) (finally (println "cleanup")))
+		      (catch Exception e (ex-message e)))`: `"in"`,
+	})
+	if out.String() != "cleanup\n" {
+		t.Errorf("expected finally to run once, got %q", out.String())
+	}
+
+	_, err := in.Eval(`(throw (ex-info "uncaught" {}))`)
+	if e, ok := err.(*Exception); !ok || e.Message != "uncaught" {
+		t.Errorf("expected the uncaught exception, got %v", err)
+	}
+	checkEvalErrors(t, in, map[string]string{
+		"(throw 1)": "throw argument should be an exception, got 1",
+		"(try (catch Exception e 1) (catch Exception e 2))": "try accepts only one catch clause",
+		"(try (catch Exception e 1) 2)":                     "try body can't follow catch or finally",
+	})
+}
@@ -0,0 +1,616 @@
+package clojura
+
+import (
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+const (
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+)
+
+// hamtEntry is either a key/value pair along with the hash of the key, or a
+// link to the next level of the trie.
+type hamtEntry struct {
+	hash  uint32
+	key   Sexpr
+	val   Sexpr
+	child *hamtNode
+}
+
+// hamtNode is a node of the hash array mapped trie. The bitmap tells which of
+// the 32 slots of the level are taken, and entries holds only those. Once all
+// bits of the hash are used up, a node keeps colliding keys in a plain list.
+type hamtNode struct {
+	bitmap  uint32
+	entries []hamtEntry
+}
+
+func (n *hamtNode) index(bit uint32) int {
+	return bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+func (n *hamtNode) with(i int, e hamtEntry) *hamtNode {
+	entries := make([]hamtEntry, len(n.entries))
+	copy(entries, n.entries)
+	entries[i] = e
+	return &hamtNode{bitmap: n.bitmap, entries: entries}
+}
+
+func (n *hamtNode) inserted(bit uint32, i int, e hamtEntry) *hamtNode {
+	entries := make([]hamtEntry, len(n.entries)+1)
+	copy(entries, n.entries[:i])
+	entries[i] = e
+	copy(entries[i+1:], n.entries[i:])
+	return &hamtNode{bitmap: n.bitmap | bit, entries: entries}
+}
+
+func (n *hamtNode) removed(bit uint32, i int) *hamtNode {
+	if len(n.entries) == 1 {
+		return nil
+	}
+	entries := make([]hamtEntry, len(n.entries)-1)
+	copy(entries, n.entries[:i])
+	copy(entries[i:], n.entries[i+1:])
+	return &hamtNode{bitmap: n.bitmap &^ bit, entries: entries}
+}
+
+func (n *hamtNode) find(shift uint, hash uint32, key Sexpr) (Sexpr, bool) {
+	if shift >= 32 {
+		for _, e := range n.entries {
+			if equal(e.key, key) {
+				return e.val, true
+			}
+		}
+		return nil, false
+	}
+	bit := uint32(1) << ((hash >> shift) & hamtMask)
+	if n.bitmap&bit == 0 {
+		return nil, false
+	}
+	e := n.entries[n.index(bit)]
+	if e.child != nil {
+		return e.child.find(shift+hamtBits, hash, key)
+	}
+	if equal(e.key, key) {
+		return e.val, true
+	}
+	return nil, false
+}
+
+// assoc returns a node with key bound to val and reports whether the key is
+// new.
+func (n *hamtNode) assoc(shift uint, hash uint32, key, val Sexpr) (*hamtNode, bool) {
+	if shift >= 32 {
+		for i, e := range n.entries {
+			if equal(e.key, key) {
+				return n.with(i, hamtEntry{hash: hash, key: key, val: val}), false
+			}
+		}
+		return n.inserted(0, len(n.entries), hamtEntry{hash: hash, key: key, val: val}), true
+	}
+	bit := uint32(1) << ((hash >> shift) & hamtMask)
+	i := n.index(bit)
+	if n.bitmap&bit == 0 {
+		return n.inserted(bit, i, hamtEntry{hash: hash, key: key, val: val}), true
+	}
+	e := n.entries[i]
+	if e.child != nil {
+		child, added := e.child.assoc(shift+hamtBits, hash, key, val)
+		return n.with(i, hamtEntry{child: child}), added
+	}
+	if equal(e.key, key) {
+		return n.with(i, hamtEntry{hash: hash, key: key, val: val}), false
+	}
+
+	// Two keys share the slot, so they move one level down.
+	child, _ := (&hamtNode{}).assoc(shift+hamtBits, e.hash, e.key, e.val)
+	child, _ = child.assoc(shift+hamtBits, hash, key, val)
+	return n.with(i, hamtEntry{child: child}), true
+}
+
+// without returns a node without key, or nil if the node becomes empty, and
+// reports whether the key was there.
+func (n *hamtNode) without(shift uint, hash uint32, key Sexpr) (*hamtNode, bool) {
+	if shift >= 32 {
+		for i, e := range n.entries {
+			if equal(e.key, key) {
+				return n.removed(0, i), true
+			}
+		}
+		return n, false
+	}
+	bit := uint32(1) << ((hash >> shift) & hamtMask)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	i := n.index(bit)
+	e := n.entries[i]
+	if e.child != nil {
+		child, removed := e.child.without(shift+hamtBits, hash, key)
+		if !removed {
+			return n, false
+		}
+		if child == nil {
+			return n.removed(bit, i), true
+		}
+		if len(child.entries) == 1 && child.entries[0].child == nil {
+			return n.with(i, child.entries[0]), true
+		}
+		return n.with(i, hamtEntry{child: child}), true
+	}
+	if equal(e.key, key) {
+		return n.removed(bit, i), true
+	}
+	return n, false
+}
+
+func (n *hamtNode) each(f func(key, val Sexpr)) {
+	for _, e := range n.entries {
+		if e.child != nil {
+			e.child.each(f)
+		} else {
+			f(e.key, e.val)
+		}
+	}
+}
+
+// HashMap is a persistent hash map backed by a hash array mapped trie. Every
+// operation returns a new map sharing structure with the old one.
+type HashMap struct {
+	cnt  int
+	root *hamtNode
+}
+
+var emptyMap = &HashMap{}
+
+// NewHashMap creates a map from a list of keys and values.
+func NewHashMap(kvs ...Sexpr) *HashMap {
+	m := emptyMap
+	for i := 0; i+1 < len(kvs); i += 2 {
+		m = m.Assoc(kvs[i], kvs[i+1])
+	}
+	return m
+}
+
+func (m *HashMap) Length() int {
+	return m.cnt
+}
+
+// Get returns the value bound to key and reports whether there is one.
+func (m *HashMap) Get(key Sexpr) (Sexpr, bool) {
+	if m.root == nil {
+		return nil, false
+	}
+	return m.root.find(0, hashOf(key), key)
+}
+
+// Assoc returns a map with key bound to val.
+func (m *HashMap) Assoc(key, val Sexpr) *HashMap {
+	root := m.root
+	if root == nil {
+		root = &hamtNode{}
+	}
+	root, added := root.assoc(0, hashOf(key), key, val)
+	cnt := m.cnt
+	if added {
+		cnt++
+	}
+	return &HashMap{cnt: cnt, root: root}
+}
+
+// Dissoc returns a map without key.
+func (m *HashMap) Dissoc(key Sexpr) *HashMap {
+	if m.root == nil {
+		return m
+	}
+	root, removed := m.root.without(0, hashOf(key), key)
+	if !removed {
+		return m
+	}
+	return &HashMap{cnt: m.cnt - 1, root: root}
+}
+
+// Each calls f for every key and value of the map.
+func (m *HashMap) Each(f func(key, val Sexpr)) {
+	if m.root != nil {
+		m.root.each(f)
+	}
+}
+
+// Seq returns the entries of the map as [key value] vectors, or nil when it
+// is empty.
+func (m *HashMap) Seq() Seq {
+	res := make([]Sexpr, 0, m.cnt)
+	m.Each(func(key, val Sexpr) {
+		res = append(res, NewVector(key, val))
+	})
+	return seqOfSlice(res)
+}
+
+func (m *HashMap) Type() CoreType {
+	return TypeMap
+}
+
+func (m *HashMap) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (m *HashMap) String() string {
+	return m.format(readableString)
+}
+
+// format prints the map, showing every key and value with show.
+func (m *HashMap) format(show func(Sexpr) string) string {
+	res := make([]string, 0, m.cnt)
+	m.Each(func(key, val Sexpr) {
+		res = append(res, show(key)+" "+show(val))
+	})
+	return "{" + strings.Join(res, ", ") + "}"
+}
+
+// Eval evaluates every key and value of a map literal.
+func (m *HashMap) Eval(c *Context) Sexpr {
+	res := emptyMap
+	m.Each(func(key, val Sexpr) {
+		res = res.Assoc(key.Eval(c), val.Eval(c))
+	})
+	return res
+}
+
+func (m *HashMap) Bool() bool {
+	return true
+}
+
+// Call looks a key up, so maps can be used as functions.
+func (m *HashMap) Call(args []Sexpr) Sexpr {
+	if len(args) != 1 && len(args) != 2 {
+		throwf("Wrong number of args (%d) passed to a map", len(args))
+	}
+	if val, ok := m.Get(args[0]); ok {
+		return val
+	}
+	if len(args) == 2 {
+		return args[1]
+	}
+	return Nil
+}
+
+// mapForm collects the keys and values of a map literal while it is parsed.
+type mapForm struct {
+	Expression
+}
+
+// build turns the parsed literal into a map, rejecting odd forms and
+// duplicate keys.
+func (f *mapForm) build() (*HashMap, error) {
+	if len(f.Elements)%2 != 0 {
+		return nil, errors.New("Map literal must contain an even number of forms")
+	}
+	m := emptyMap
+	for i := 0; i < len(f.Elements); i += 2 {
+		if _, ok := m.Get(f.Elements[i]); ok {
+			return nil, errors.New("Duplicate key: " + f.Elements[i].String())
+		}
+		m = m.Assoc(f.Elements[i], f.Elements[i+1])
+	}
+	return m, nil
+}
+
+// hashOf returns a hash of s that is consistent with equal.
+func hashOf(s Sexpr) uint32 {
+	switch t := s.(type) {
+	case nilValue:
+		return 0
+	case Number:
+		return hashInt(uint64(t))
+	case BigInt:
+		if t.val.IsInt64() {
+			return hashInt(uint64(t.val.Int64()))
+		}
+		return hashString("N" + t.val.String())
+	case Ratio:
+		return hashString("R" + t.val.String())
+	case Float:
+		if t == 0 {
+			return hashInt(math.Float64bits(0))
+		}
+		return hashInt(math.Float64bits(float64(t)))
+	case Boolean:
+		if t {
+			return 1231
+		}
+		return 1237
+	case String:
+		return hashString("S" + string(t))
+	case Literal:
+		return hashString("L" + string(t))
+	case *Keyword:
+		return hashString("K" + t.name)
+	case *Vector, Seq:
+		var h uint32 = 1
+		for _, item := range sequentialItems(t) {
+			h = 31*h + hashOf(item)
+		}
+		return h
+	case *HashMap:
+		var h uint32
+		t.Each(func(key, val Sexpr) {
+			h += hashOf(key) ^ hashOf(val)
+		})
+		return h
+	case *Set:
+		var h uint32
+		for _, item := range t.Slice() {
+			h += hashOf(item)
+		}
+		return h
+	}
+	return uint32(s.Type())
+}
+
+func hashString(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+func hashInt(n uint64) uint32 {
+	n ^= n >> 33
+	n *= 0xff51afd7ed558ccd
+	n ^= n >> 33
+	return uint32(n)
+}
+
+// mapsEqual reports whether both maps hold equal values for the same keys.
+func mapsEqual(a, b *HashMap) bool {
+	if a.Length() != b.Length() {
+		return false
+	}
+	res := true
+	a.Each(func(key, val Sexpr) {
+		if other, ok := b.Get(key); !ok || !equal(val, other) {
+			res = false
+		}
+	})
+	return res
+}
+
+// mapArg raises an exception unless s is a map.
+func mapArg(name string, s Sexpr) *HashMap {
+	m, ok := s.(*HashMap)
+	if !ok {
+		throwf("%s argument should be a map, got %s", name, s)
+	}
+	return m
+}
+
+// get looks key up in a map, a set or a vector.
+func get(coll, key Sexpr) (Sexpr, bool) {
+	switch t := coll.(type) {
+	case *HashMap:
+		return t.Get(key)
+	case *Set:
+		if t.Contains(key) {
+			return key, true
+		}
+	case *Vector:
+		if i, ok := key.(Number); ok {
+			return t.Nth(int(i))
+		}
+	}
+	return nil, false
+}
+
+// assoc binds key to val in a map or a vector.
+func assoc(coll, key, val Sexpr) Sexpr {
+	switch t := coll.(type) {
+	case nilValue:
+		return emptyMap.Assoc(key, val)
+	case *HashMap:
+		return t.Assoc(key, val)
+	case *Vector:
+		idx := numberArg("assoc", key)
+		res, ok := t.Assoc(int(idx), val)
+		if !ok {
+			throwf("Index %d out of bounds", idx)
+		}
+		return res
+	}
+	throwf("assoc argument should be a map or a vector, got %s", coll)
+	return nil
+}
+
+func coreHashMap(args []Sexpr) Sexpr {
+	if len(args)%2 != 0 {
+		throwf("hash-map expects key value pairs")
+	}
+	return NewHashMap(args...)
+}
+
+func coreIsMap(args []Sexpr) Sexpr {
+	checkArity("map?", args, 1)
+	_, ok := args[0].(*HashMap)
+	return Boolean(ok)
+}
+
+func coreGet(args []Sexpr) Sexpr {
+	if len(args) != 2 && len(args) != 3 {
+		throwf("Wrong number of args (%d) passed to get", len(args))
+	}
+	if val, ok := get(args[0], args[1]); ok {
+		return val
+	}
+	if len(args) == 3 {
+		return args[2]
+	}
+	return Nil
+}
+
+func coreAssoc(args []Sexpr) Sexpr {
+	if len(args) < 3 || len(args)%2 != 1 {
+		throwf("assoc expects a collection and key value pairs")
+	}
+	res := args[0]
+	for i := 1; i < len(args); i += 2 {
+		res = assoc(res, args[i], args[i+1])
+	}
+	return res
+}
+
+func coreDissoc(args []Sexpr) Sexpr {
+	if len(args) < 1 {
+		throwf("Wrong number of args (0) passed to dissoc")
+	}
+	if args[0] == Nil {
+		return Nil
+	}
+	m := mapArg("dissoc", args[0])
+	for _, key := range args[1:] {
+		m = m.Dissoc(key)
+	}
+	return m
+}
+
+func coreContains(args []Sexpr) Sexpr {
+	checkArity("contains?", args, 2)
+	if args[0] == Nil {
+		return False
+	}
+	switch args[0].(type) {
+	case *HashMap, *Set, *Vector:
+		_, ok := get(args[0], args[1])
+		return Boolean(ok)
+	}
+	throwf("contains? not supported on %s", args[0])
+	return nil
+}
+
+// coreKeys returns the keys of a map, or nil when it is empty or nil.
+func coreKeys(args []Sexpr) Sexpr {
+	checkArity("keys", args, 1)
+	if args[0] == Nil {
+		return Nil
+	}
+	var res []Sexpr
+	mapArg("keys", args[0]).Each(func(key, val Sexpr) {
+		res = append(res, key)
+	})
+	if len(res) == 0 {
+		return Nil
+	}
+	return listOf(res)
+}
+
+// coreVals returns the vals of a map, or nil when it is empty or nil.
+func coreVals(args []Sexpr) Sexpr {
+	checkArity("vals", args, 1)
+	if args[0] == Nil {
+		return Nil
+	}
+	var res []Sexpr
+	mapArg("vals", args[0]).Each(func(key, val Sexpr) {
+		res = append(res, val)
+	})
+	if len(res) == 0 {
+		return Nil
+	}
+	return listOf(res)
+}
+
+func coreMerge(args []Sexpr) Sexpr {
+	var res *HashMap
+	for _, arg := range args {
+		if arg == Nil {
+			continue
+		}
+		m := mapArg("merge", arg)
+		if res == nil {
+			res = m
+			continue
+		}
+		m.Each(func(key, val Sexpr) {
+			res = res.Assoc(key, val)
+		})
+	}
+	if res == nil {
+		return Nil
+	}
+	return res
+}
+
+func coreUpdate(args []Sexpr) Sexpr {
+	if len(args) < 3 {
+		throwf("Wrong number of args (%d) passed to update", len(args))
+	}
+	coll, key, f := args[0], args[1], args[2]
+	old, ok := get(coll, key)
+	if !ok {
+		old = Nil
+	}
+	return assoc(coll, key, call(f, append([]Sexpr{old}, args[3:]...)))
+}
+
+// pathArg returns the keys of a get-in style path.
+func pathArg(name string, s Sexpr) []Sexpr {
+	if !isSequential(s) {
+		throwf("%s path should be a vector, got %s", name, s)
+	}
+	return sequentialItems(s)
+}
+
+func coreGetIn(args []Sexpr) Sexpr {
+	if len(args) != 2 && len(args) != 3 {
+		throwf("Wrong number of args (%d) passed to get-in", len(args))
+	}
+	res := args[0]
+	for _, key := range pathArg("get-in", args[1]) {
+		val, ok := get(res, key)
+		if !ok {
+			if len(args) == 3 {
+				return args[2]
+			}
+			return Nil
+		}
+		res = val
+	}
+	return res
+}
+
+// assocIn binds the value at path in coll to val, creating maps for the
+// missing levels.
+func assocIn(coll Sexpr, path []Sexpr, val Sexpr) Sexpr {
+	if len(path) == 1 {
+		return assoc(coll, path[0], val)
+	}
+	inner, ok := get(coll, path[0])
+	if !ok {
+		inner = Nil
+	}
+	return assoc(coll, path[0], assocIn(inner, path[1:], val))
+}
+
+func coreAssocIn(args []Sexpr) Sexpr {
+	checkArity("assoc-in", args, 3)
+	path := pathArg("assoc-in", args[1])
+	if len(path) == 0 {
+		throwf("assoc-in path should not be empty")
+	}
+	return assocIn(args[0], path, args[2])
+}
+
+func coreUpdateIn(args []Sexpr) Sexpr {
+	if len(args) < 3 {
+		throwf("Wrong number of args (%d) passed to update-in", len(args))
+	}
+	path := pathArg("update-in", args[1])
+	if len(path) == 0 {
+		throwf("update-in path should not be empty")
+	}
+	old := coreGetIn(args[:2])
+	return assocIn(args[0], path, call(args[2], append([]Sexpr{old}, args[3:]...)))
+}
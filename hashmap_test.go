@@ -0,0 +1,101 @@
+package clojura
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHashMapAssocDissoc(t *testing.T) {
+	const n = 20000
+	m := NewHashMap()
+	for i := 0; i < n; i++ {
+		m = m.Assoc(Number(i), Number(i*i))
+	}
+	if m.Length() != n {
+		t.Fatalf("expected %d entries, got %d", n, m.Length())
+	}
+	half := m
+	for i := 0; i < n; i += 2 {
+		half = half.Dissoc(Number(i))
+	}
+	for i := 0; i < n; i++ {
+		if val, ok := m.Get(Number(i)); !ok || val != Number(i*i) {
+			t.Fatalf("expected %d for %d, got %v", i*i, i, val)
+		}
+		_, ok := half.Get(Number(i))
+		if ok != (i%2 == 1) {
+			t.Fatalf("unexpected presence of %d: %v", i, ok)
+		}
+	}
+	if half.Length() != n/2 {
+		t.Errorf("expected %d entries, got %d", n/2, half.Length())
+	}
+}
+
+func TestHashMapCollisions(t *testing.T) {
+	var root *hamtNode = &hamtNode{}
+	keys := []Sexpr{String("a"), String("b"), String("c")}
+	for i, key := range keys {
+		root, _ = root.assoc(0, 42, key, Number(i))
+	}
+	for i, key := range keys {
+		if val, ok := root.find(0, 42, key); !ok || val != Number(i) {
+			t.Errorf("expected %d for %s, got %v", i, key, val)
+		}
+	}
+	root, _ = root.without(0, 42, String("b"))
+	if _, ok := root.find(0, 42, String("b")); ok {
+		t.Error("b should have been removed")
+	}
+	if val, ok := root.find(0, 42, String("c")); !ok || val != Number(2) {
+		t.Errorf("expected 2 for c, got %v", val)
+	}
+}
+
+func TestCommasAreWhitespace(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	tests := map[string]string{
+		"(get {:a 1, :b 2} :b)":                  "2",
+		"[1,2,\r\n3]":                            "[1 2 3]",
+		"(let [{a :a, b :b} {:a 1 :b 2}] [a b])": "[1 2]",
+		"(= {:a [1 2], \"b\" {:c nil}} {\"b\" {:c nil}, :a [1 2]})": "true",
+	}
+	checkEval(t, in, tests)
+
+	// A printed map reads back as an equal map.
+	m, err := in.Eval(`{:a 1 :b "x" :c [1 2] :d {:e nil}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(m.String(), ", ") {
+		t.Fatalf("expected the entries of %s to be separated by commas", m)
+	}
+	back, err := in.Eval("'" + m.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(m, back) {
+		t.Errorf("expected %s to read back as an equal map, got %s", m, back)
+	}
+}
+
+func TestKeysAndValsFollowSeqOrder(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	res, err := in.Eval(`
+(def m (reduce (fn [m i] (assoc m (keyword (str "k" i)) i)) {} (range 40)))
+[(= (keys m) (map first m)) (= (vals m) (map (fn [[k v]] v) m))]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.String() != "[true true]" {
+		t.Errorf("expected keys and vals in the order of seq, got %s", res)
+	}
+	res, err = in.Eval("[(keys {}) (vals {}) (keys nil) (vals nil)]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.String() != "[nil nil nil nil]" {
+		t.Errorf("expected no keys and vals, got %s", res)
+	}
+}
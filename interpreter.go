@@ -0,0 +1,191 @@
+package clojura
+
+import (
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Interpreter is a self-contained clojura runtime. It owns its namespaces,
+// output writers and logger, so any number of interpreters can live in one
+// process without seeing each other's definitions.
+type Interpreter struct {
+	root       *Context
+	namespaces map[Literal]*Namespace
+	// ns is the current namespace and core the one of the core library.
+	ns     *Namespace
+	core   *Namespace
+	out    io.Writer
+	errOut io.Writer
+	log    *Logger
+	rand   *rand.Rand
+	stack  []Frame
+	// loadPath holds the directories files are searched in, loading the
+	// absolute paths of the files being loaded, innermost last, and loaded
+	// the ones load and require are done with.
+	loadPath []string
+	loading  []string
+	loaded   map[string]bool
+	// vm tells whether code is compiled to bytecode and run on the virtual
+	// machine rather than evaluated by walking the analyzed forms.
+	vm bool
+}
+
+// NewInterpreter creates an interpreter that prints to out, logs to errOut and
+// has the core library already loaded. Code is evaluated in the user
+// namespace.
+func NewInterpreter(out, errOut io.Writer) *Interpreter {
+	in := &Interpreter{
+		namespaces: map[Literal]*Namespace{},
+		loaded:     map[string]bool{},
+		out:        out,
+		errOut:     errOut,
+		log:        NewLogger(Info, errOut),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	in.core = in.namespace(coreNamespace)
+	in.inNs(in.core)
+	in.root = newCoreContext(in)
+	if err := in.catch(func() { in.loadNamespace(coreNamespace) }); err != nil {
+		panic("clojura: failed to load core library: " + err.Error())
+	}
+	in.inNs(in.namespace("user"))
+	return in
+}
+
+// New creates an interpreter bound to the process' stdout and stderr.
+func New() *Interpreter {
+	return NewInterpreter(os.Stdout, os.Stderr)
+}
+
+// Logger returns the interpreter's logger.
+func (in *Interpreter) Logger() *Logger {
+	return in.log
+}
+
+// UseVM chooses how the interpreter runs code: compiled to bytecode for its
+// virtual machine when on is set, or by walking the analyzed forms, which is
+// the default.
+func (in *Interpreter) UseVM(on bool) {
+	in.vm = on
+}
+
+// Context returns the root context of the interpreter.
+func (in *Interpreter) Context() *Context {
+	return in.root
+}
+
+// Define binds name to value in the current namespace.
+func (in *Interpreter) Define(name string, value Sexpr) {
+	in.root.Set(Literal(name), orNil(value))
+}
+
+// Names returns the sorted names of the special forms and of the globals
+// bound in the current namespace.
+func (in *Interpreter) Names() []string {
+	vars := in.mappings(in.ns)
+	names := make([]string, 0, len(specialForms)+len(vars))
+	for name := range specialForms {
+		names = append(names, string(name))
+	}
+	for name, v := range vars {
+		if v.value != nil {
+			names = append(names, string(name))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Eval evaluates every form in src and returns the value of the last one.
+func (in *Interpreter) Eval(src string) (Sexpr, error) {
+	return in.EvalReader(strings.NewReader(src))
+}
+
+// EvalReader evaluates every form read from r and returns the value of the
+// last one.
+func (in *Interpreter) EvalReader(r io.Reader) (Sexpr, error) {
+	return in.evalReader(r, "")
+}
+
+// LoadFile evaluates the file with the given name. The ns form a file starts
+// with only applies to the file: the current namespace is restored once it
+// is loaded. Files loaded by the file are looked up next to it first.
+func (in *Interpreter) LoadFile(name string) error {
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return in.load(f, path, name)
+}
+
+// load evaluates the source of the file at path read from r, restoring the
+// current namespace afterwards. Positions in the source are reported in the
+// file with the given name. Once loaded, the file is not loaded again by
+// load and require.
+func (in *Interpreter) load(r io.Reader, path, name string) error {
+	defer in.inNs(in.ns)
+	in.loading = append(in.loading, path)
+	defer func() {
+		in.loading = in.loading[:len(in.loading)-1]
+	}()
+	if _, err := in.evalReader(r, name); err != nil {
+		return err
+	}
+	in.loaded[path] = true
+	return nil
+}
+
+func (in *Interpreter) evalReader(r io.Reader, file string) (Sexpr, error) {
+	lexer := NewFileLexer(r, file)
+	parser := NewParser(lexer)
+	start := time.Now()
+	sexpr, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	in.log.Debug("Parsed in ", time.Since(start))
+
+	res := Nil
+	if err := in.catch(func() {
+		for i, s := range sexpr {
+			res = in.evalTop(s, parser.starts[i])
+		}
+	}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// evalTop analyzes and evaluates a top-level form read at pos. The forms of
+// a top-level do are taken one at a time, so that a form can refer to the
+// namespaces and vars the forms before it create.
+func (in *Interpreter) evalTop(s Sexpr, pos Pos) Sexpr {
+	if e, ok := s.(*Expression); ok && len(e.Elements) > 0 && e.Elements[0] == Literal("do") {
+		res := Nil
+		for _, f := range e.Elements[1:] {
+			res = in.evalTop(f, e.Pos)
+		}
+		return res
+	}
+	form := in.analyzeAt(s, pos)
+	if in.vm {
+		return in.execute(compile(form), in.root, nil)
+	}
+	return form.Eval(in.root)
+}
+
+// NewBuiltin wraps a Go function so it can be bound with Define and called
+// from clojura code.
+func NewBuiltin(fn func([]Sexpr) Sexpr) Sexpr {
+	return coreF(fn)
+}
@@ -0,0 +1,249 @@
+package clojura
+
+import (
+	"bytes"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+// checkEval evaluates the source of every test with in and checks that it
+// prints as expected.
+func checkEval(t *testing.T, in *Interpreter, tests map[string]string) {
+	t.Helper()
+	for src, expected := range tests {
+		res, err := in.Eval(src)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if res.String() != expected {
+			t.Errorf("%s: expected %s, got %s", src, expected, res)
+		}
+	}
+}
+
+// checkEvalErrors evaluates the source of every test with in and checks
+// that it fails with an error containing the expected message.
+func checkEvalErrors(t *testing.T, in *Interpreter, errors map[string]string) {
+	t.Helper()
+	for src, expected := range errors {
+		_, err := in.Eval(src)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected error %q, got %v", src, expected, err)
+		}
+	}
+}
+
+func TestInterpretersAreIsolated(t *testing.T) {
+	var out1, out2 bytes.Buffer
+	in1 := NewInterpreter(&out1, &out1)
+	in2 := NewInterpreter(&out2, &out2)
+
+	in1.Define("x", Number(1))
+	if _, err := in2.Eval("(def x 2)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in1.Eval("(println x)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in2.Eval("(println x)"); err != nil {
+		t.Fatal(err)
+	}
+	if out1.String() != "1\n" || out2.String() != "2\n" {
+		t.Errorf("unexpected output %q and %q", out1.String(), out2.String())
+	}
+}
+
+func TestEvalReturnsLastValue(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	res, err := in.Eval("(def a 40) (+ a 2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != Number(42) {
+		t.Errorf("expected 42, got %v", res)
+	}
+}
+
+func TestExceptionTrace(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	_, err := in.Eval("(def f (fn (x)\n  (+ x \"a\")))\n(f 1)")
+	e, ok := err.(*Exception)
+	if !ok {
+		t.Fatalf("expected an exception, got %v", err)
+	}
+	expected := []string{
+		"at + (NO_SOURCE_FILE:2:3)",
+		"at f (NO_SOURCE_FILE:3:1)",
+	}
+	if len(e.Trace) != len(expected) {
+		t.Fatalf("unexpected trace %v", e.Trace)
+	}
+	for i, frame := range e.Trace {
+		if frame.String() != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], frame)
+		}
+	}
+}
+
+func TestRandom(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	tests := map[string][2]Number{
+		"(random 5 8)": {5, 8},
+		"(random 8 5)": {5, 8},
+		"(random -9223372036854775807 9223372036854775807)": {-9223372036854775807, 9223372036854775807},
+		"(random -9223372036854775808 0)":                   {-9223372036854775808, 0},
+	}
+	for src, bounds := range tests {
+		for i := 0; i < 20; i++ {
+			res, err := in.Eval(src)
+			if err != nil {
+				t.Fatalf("%s: %v", src, err)
+			}
+			if n, ok := res.(Number); !ok || n < bounds[0] || n >= bounds[1] {
+				t.Fatalf("%s: %v out of range", src, res)
+			}
+		}
+	}
+	if _, err := in.Eval("(random 1 1)"); err == nil {
+		t.Error("expected an empty range error")
+	}
+}
+
+func TestRuntimeErrorsAreExceptions(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	in.Define("boom", coreF(func(args []Sexpr) Sexpr {
+		return args[1]
+	}))
+	res, err := in.Eval("(try (boom) (catch Exception e (ex-message e)))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(res.String(), "index out of range") {
+		t.Errorf("expected the runtime error to be caught, got %s", res)
+	}
+	_, err = in.Eval("(boom)")
+	if _, ok := err.(*Exception); !ok || !strings.Contains(err.Error(), "index out of range") {
+		t.Errorf("expected an index out of range exception, got %v", err)
+	}
+}
+
+func TestTopLevelAtoms(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	tests := map[string]string{
+		"42":             "42",
+		"(def x 1) x":    "1",
+		":k":             ":k",
+		`"s"`:            `"s"`,
+		"'sym":           "sym",
+		"(def y 2)\ny\n": "2",
+		"nil":            "nil",
+	}
+	checkEval(t, in, tests)
+}
+
+func TestFunctionArities(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	_, err := in.Eval(`
+(def f (fn f ([] 0) ([x] x) ([x y & more] [x y more])))
+(def sum (fn [acc & xs] (if (seq xs) (recur (+ acc (head xs)) (tail xs)) acc)))
+(def g (fn g ([x] x) ([x y] y)))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"(f)":                "0",
+		"(f 1)":              "1",
+		"(f 1 2)":            "[1 2 nil]",
+		"(f 1 2 3 4)":        "[1 2 (3 4)]",
+		"((fn [& xs] xs) 1)": "(1)",
+		"(sum 0 1 2 3 4)":    "10",
+		"(try (g) (catch Exception e (ex-message e)))": `"Wrong number of args (0) passed to g, accepts [x] [x y]"`,
+	}
+	checkEval(t, in, tests)
+}
+
+func TestLetScope(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	tests := map[string]string{
+		"(let [a 1 b (+ a 1)] (+ a b))":   "3",
+		"(do (def a 1) (let [a 2] a) a)":  "1",
+		"(let [x 1] (let [x (+ x 1)] x))": "2",
+		"(letfn [(ev? [n] (if (= n 0) true (od? (- n 1)))) (od? [n] (if (= n 0) false (ev? (- n 1))))] (ev? 10))": "true",
+	}
+	checkEval(t, in, tests)
+	if _, err := in.Eval("(do (let [z 1] z) z)"); err == nil || !strings.Contains(err.Error(), "Unable to resolve symbol: z") {
+		t.Errorf("expected z to be unbound outside of let, got %v", err)
+	}
+}
+
+func TestLoopRecur(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	tests := map[string]string{
+		"(loop [i 0 acc 0] (if (< i 5) (recur (+ i 1) (+ acc i)) acc))":       "10",
+		"(loop [[x & xs] [1 2 3] acc []] (if x (recur xs (conj acc x)) acc))": "[1 2 3]",
+		"((fn [n] (let [m (- n 1)] (if (> m 0) (recur m) m))) 5)":             "0",
+	}
+	checkEval(t, in, tests)
+
+	for _, src := range []string{
+		"(recur 1)",
+		"(fn [n] (+ 1 (recur n)))",
+		"(loop [i 0] (do (recur i) 1))",
+		"(fn [n] (if (recur n) 1 2))",
+	} {
+		_, err := in.Eval(src)
+		if err == nil || !strings.Contains(err.Error(), "Can only recur from tail position") {
+			t.Errorf("%s: expected tail position error, got %v", src, err)
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	// Without tail calls the mutual recursion below needs far more stack.
+	defer debug.SetMaxStack(debug.SetMaxStack(8 << 20))
+
+	tests := map[string]string{
+		`(do (declare od?)
+		     (def ev? (fn [n] (if (= n 0) true (od? (- n 1)))))
+		     (def od? (fn [n] (if (= n 0) false (ev? (- n 1)))))
+		     (ev? 100001))`: "false",
+		`(do (def down (fn [n] (let [m (- n 1)] (do (if (> m 0) (down m) :done)))))
+		     (down 100000))`: ":done",
+		`(do (def bounce (fn [n] (if (= n 0) :landed (fn [] (bounce (- n 1))))))
+		     (trampoline bounce 100000))`: ":landed",
+		`(do (defn f [n] (and true (if (> n 0) (f (- n 1)) :ok)))
+		     (f 100000))`: ":ok",
+		`(do (defn g [n] (or false (if (> n 0) (g (- n 1)) :ok)))
+		     (g 100000))`: ":ok",
+	}
+	for _, vm := range []bool{false, true} {
+		in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+		in.UseVM(vm)
+		checkEval(t, in, tests)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	for _, vm := range []bool{false, true} {
+		in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+		in.UseVM(vm)
+		if _, err := in.Eval("(defn g [n] (if (= n 0) 0 (+ 1 (g (- n 1)))))"); err != nil {
+			t.Fatal(err)
+		}
+		if res, err := in.Eval("(g 1000)"); err != nil || res != Number(1000) {
+			t.Errorf("expected 1000, got %v, %v", res, err)
+		}
+		if _, err := in.Eval("(g 10000000)"); err == nil || err.Error() != "StackOverflowError" {
+			t.Errorf("expected a stack overflow, got %v", err)
+		}
+		res, err := in.Eval("(try (g 10000000) (catch Exception e (ex-message e)))")
+		if err != nil || res.String() != `"StackOverflowError"` {
+			t.Errorf("expected the stack overflow to be caught, got %v, %v", res, err)
+		}
+		if res, err := in.Eval("(g 10)"); err != nil || res != Number(10) {
+			t.Errorf("expected the stack to be unwound, got %v, %v", res, err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package clojura
+
+import (
+	"errors"
+	"sync"
+)
+
+// Keyword is an interned name that evaluates to itself. There is only ever
+// one Keyword per name, so keywords compare by identity.
+type Keyword struct {
+	name string
+}
+
+var keywords = struct {
+	sync.Mutex
+	table map[string]*Keyword
+}{table: make(map[string]*Keyword)}
+
+// Intern returns the keyword with the given name, without the leading colon.
+func Intern(name string) *Keyword {
+	keywords.Lock()
+	defer keywords.Unlock()
+	k, ok := keywords.table[name]
+	if !ok {
+		k = &Keyword{name: name}
+		keywords.table[name] = k
+	}
+	return k
+}
+
+func (k *Keyword) Name() string {
+	return k.name
+}
+
+func (k *Keyword) Bool() bool {
+	return true
+}
+
+func (k *Keyword) String() string {
+	return ":" + k.name
+}
+
+func (k *Keyword) Type() CoreType {
+	return TypeKeyword
+}
+
+func (k *Keyword) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (k *Keyword) Eval(c *Context) Sexpr {
+	return k
+}
+
+// Call looks the keyword up in a map, so (:name user) works like
+// (get user :name).
+func (k *Keyword) Call(args []Sexpr) Sexpr {
+	if len(args) != 1 && len(args) != 2 {
+		throwf("Wrong number of args (%d) passed to %s", len(args), k)
+	}
+	if val, ok := get(args[0], k); ok {
+		return val
+	}
+	if len(args) == 2 {
+		return args[1]
+	}
+	return Nil
+}
+
+func coreKeyword(args []Sexpr) Sexpr {
+	checkArity("keyword", args, 1)
+	switch t := args[0].(type) {
+	case *Keyword:
+		return t
+	case String:
+		return Intern(string(t))
+	case Literal:
+		return Intern(string(t))
+	}
+	throwf("keyword argument should be a string, got %s", args[0])
+	return nil
+}
+
+func coreIsKeyword(args []Sexpr) Sexpr {
+	checkArity("keyword?", args, 1)
+	_, ok := args[0].(*Keyword)
+	return Boolean(ok)
+}
+
+// coreName returns the name of a keyword or a symbol without its namespace,
+// or a string unchanged.
+func coreName(args []Sexpr) Sexpr {
+	checkArity("name", args, 1)
+	switch t := args[0].(type) {
+	case *Keyword:
+		_, name, _ := splitSymbol(Literal(t.name))
+		return String(name)
+	case String:
+		return t
+	case Literal:
+		_, name, _ := splitSymbol(t)
+		return String(name)
+	}
+	throwf("name argument should be a keyword, a symbol or a string, got %s", args[0])
+	return nil
+}
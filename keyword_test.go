@@ -0,0 +1,23 @@
+package clojura
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKeywords(t *testing.T) {
+	tests := map[string]string{
+		":a":                     ":a",
+		"(keyword? :a)":          "true",
+		"(= :a (keyword \"a\"))": "true",
+		"(:b {:a 1 :b 2})":       "2",
+		"(:c {:a 1} :none)":      ":none",
+		"[(name :a) (name :a/b) (name 'x/y) (name \"a/b\")]": `["a" "b" "y" "a/b"]`,
+		"(name '/)": `"/"`,
+	}
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	checkEval(t, in, tests)
+	if Intern("a") != Intern("a") {
+		t.Error("expected keywords with the same name to be identical")
+	}
+}
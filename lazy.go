@@ -0,0 +1,244 @@
+package clojura
+
+import (
+	"errors"
+)
+
+// LazySeq is a sequence whose elements are computed when they are first
+// needed. fn yields the sequence the lazy one stands for; it is called once
+// and its result is cached, so the elements are computed once as well.
+type LazySeq struct {
+	fn  func() Sexpr
+	seq Seq
+}
+
+func newLazySeq(fn func() Sexpr) *LazySeq {
+	return &LazySeq{fn: fn}
+}
+
+// Seq calls fn unless it was called already and returns the sequence it
+// yielded, or nil when it is empty. Lazy sequences yielding lazy sequences
+// are realized in a loop, so skipping a long run of elements does not grow
+// the stack.
+func (l *LazySeq) Seq() Seq {
+	if l.fn == nil {
+		return l.seq
+	}
+	res := l.fn()
+	l.fn = nil
+	pending := []*LazySeq{l}
+	for {
+		inner, ok := res.(*LazySeq)
+		if !ok || inner.fn == nil {
+			break
+		}
+		res = inner.fn()
+		inner.fn = nil
+		pending = append(pending, inner)
+	}
+	s := seqOf(res)
+	for _, p := range pending {
+		p.seq = s
+	}
+	return s
+}
+
+func (l *LazySeq) First() Sexpr {
+	if s := l.Seq(); s != nil {
+		return s.First()
+	}
+	return Nil
+}
+
+func (l *LazySeq) Rest() Seq {
+	if s := l.Seq(); s != nil {
+		return s.Rest()
+	}
+	return NewList()
+}
+
+func (l *LazySeq) Type() CoreType {
+	return TypeList
+}
+
+func (l *LazySeq) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (l *LazySeq) String() string {
+	return formatSeq(l, readableString)
+}
+
+func (l *LazySeq) Eval(c *Context) Sexpr {
+	return l
+}
+
+// Bool returns true without realizing the sequence: even an empty lazy
+// sequence is truthy.
+func (l *LazySeq) Bool() bool {
+	return true
+}
+
+// Show returns the printed form of s. The lazy sequences s holds are
+// realized, and an exception raised while realizing them is returned.
+func (in *Interpreter) Show(s Sexpr) (res string, err error) {
+	if e := in.catch(func() { res = s.String() }); e != nil {
+		return "", e
+	}
+	return res, nil
+}
+
+func coreLazySeq(args []Sexpr) Sexpr {
+	checkArity("lazy-seq*", args, 1)
+	f := args[0]
+	return newLazySeq(func() Sexpr {
+		return call(f, nil)
+	})
+}
+
+// rangeSeq returns the numbers from start to end, excluded, by step. It is
+// infinite when end is nil.
+func rangeSeq(start, end, step Sexpr) *LazySeq {
+	return newLazySeq(func() Sexpr {
+		if end != nil {
+			c, _ := compareNum("range", start, end)
+			dir, _ := compareNum("range", step, Number(0))
+			if dir >= 0 && c >= 0 || dir < 0 && c <= 0 {
+				return Nil
+			}
+		}
+		return &Cons{start, rangeSeq(addPromoteOp.apply(start, step), end, step)}
+	})
+}
+
+func coreRange(args []Sexpr) Sexpr {
+	var start, end, step Sexpr = Number(0), nil, Number(1)
+	switch len(args) {
+	case 0:
+	case 1:
+		end = args[0]
+	case 2:
+		start, end = args[0], args[1]
+	case 3:
+		start, end, step = args[0], args[1], args[2]
+	default:
+		throwf("Wrong number of args (%d) passed to range", len(args))
+	}
+	for _, arg := range args {
+		category("range", arg)
+	}
+	return rangeSeq(start, end, step)
+}
+
+func takeSeq(n Sexpr, coll Sexpr) *LazySeq {
+	return newLazySeq(func() Sexpr {
+		if c, _ := compareNum("take", n, Number(0)); c <= 0 {
+			return Nil
+		}
+		s := seqOf(coll)
+		if s == nil {
+			return Nil
+		}
+		return &Cons{s.First(), takeSeq(subOp.apply(n, Number(1)), s.Rest())}
+	})
+}
+
+func coreTake(args []Sexpr) Sexpr {
+	checkArity("take", args, 2)
+	category("take", args[0])
+	return takeSeq(args[0], args[1])
+}
+
+func coreDrop(args []Sexpr) Sexpr {
+	checkArity("drop", args, 2)
+	n, coll := args[0], args[1]
+	category("drop", n)
+	return newLazySeq(func() Sexpr {
+		s := seqOf(coll)
+		for ; s != nil; s = s.Rest().Seq() {
+			if c, _ := compareNum("drop", n, Number(0)); c <= 0 {
+				break
+			}
+			n = subOp.apply(n, Number(1))
+		}
+		return orNilSeq(s)
+	})
+}
+
+func takeWhileSeq(pred, coll Sexpr) *LazySeq {
+	return newLazySeq(func() Sexpr {
+		s := seqOf(coll)
+		if s == nil || !call(pred, []Sexpr{s.First()}).Bool() {
+			return Nil
+		}
+		return &Cons{s.First(), takeWhileSeq(pred, s.Rest())}
+	})
+}
+
+func coreTakeWhile(args []Sexpr) Sexpr {
+	checkArity("take-while", args, 2)
+	return takeWhileSeq(args[0], args[1])
+}
+
+func coreDropWhile(args []Sexpr) Sexpr {
+	checkArity("drop-while", args, 2)
+	pred, coll := args[0], args[1]
+	return newLazySeq(func() Sexpr {
+		s := seqOf(coll)
+		for s != nil && call(pred, []Sexpr{s.First()}).Bool() {
+			s = s.Rest().Seq()
+		}
+		return orNilSeq(s)
+	})
+}
+
+// iterateSeq returns x, (f x), (f (f x)) and so on. Every application of f
+// is made when the element it computes is needed.
+func iterateSeq(f, x Sexpr) *LazySeq {
+	return newLazySeq(func() Sexpr {
+		return &Cons{x, newLazySeq(func() Sexpr {
+			return iterateSeq(f, call(f, []Sexpr{x}))
+		})}
+	})
+}
+
+func coreIterate(args []Sexpr) Sexpr {
+	checkArity("iterate", args, 2)
+	return iterateSeq(args[0], args[1])
+}
+
+func coreRepeat(args []Sexpr) Sexpr {
+	switch len(args) {
+	case 1:
+		c := &Cons{first: args[0]}
+		c.more = c
+		return c
+	case 2:
+		return takeSeq(args[0], coreRepeat(args[1:]))
+	}
+	throwf("Wrong number of args (%d) passed to repeat", len(args))
+	return nil
+}
+
+// cycleSeq returns the elements of s from those of cur on, starting over
+// from the first one of s whenever they run out.
+func cycleSeq(s, cur Seq) *LazySeq {
+	return newLazySeq(func() Sexpr {
+		if cur = cur.Seq(); cur == nil {
+			cur = s
+		}
+		return &Cons{cur.First(), cycleSeq(s, cur.Rest())}
+	})
+}
+
+func coreCycle(args []Sexpr) Sexpr {
+	checkArity("cycle", args, 1)
+	coll := args[0]
+	return newLazySeq(func() Sexpr {
+		s := seqOf(coll)
+		if s == nil {
+			return Nil
+		}
+		return cycleSeq(s, s)
+	})
+}
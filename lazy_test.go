@@ -0,0 +1,64 @@
+package clojura
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLazySeqs(t *testing.T) {
+	tests := map[string]string{
+		"(range 5)":                             "(0 1 2 3 4)",
+		"(range 2 5)":                           "(2 3 4)",
+		"(range 10 0 -3)":                       "(10 7 4 1)",
+		"(range 0 1 1/2)":                       "(0 1/2)",
+		"(range 0)":                             "()",
+		"(take 3 (range))":                      "(0 1 2)",
+		"(take 3 (drop 5 (range)))":             "(5 6 7)",
+		"(take-while (fn [x] (< x 3)) (range))": "(0 1 2)",
+		"(take 2 (drop-while (fn [x] (< x 3)) (range)))":         "(3 4)",
+		"(take 4 (iterate (fn [x] (* x 2)) 1))":                  "(1 2 4 8)",
+		"(take 3 (repeat :x))":                                   "(:x :x :x)",
+		"(repeat 2 1)":                                           "(1 1)",
+		"(take 5 (cycle [1 2]))":                                 "(1 2 1 2 1)",
+		"(cycle [])":                                             "()",
+		"(take 3 (map inc (filter odd? (range))))":               "(2 4 6)",
+		"(reduce + 0 (take 100 (range)))":                        "4950",
+		"(head (drop 100000 (range)))":                           "100000",
+		"(= (range 3) '(0 1 2) [0 1 2])":                         "true",
+		"(if (seq (filter odd? [2 4])) :some :none)":             ":none",
+		"(if (filter odd? [2 4]) :some :none)":                   ":some",
+		"[(if () 1 2) (if (rest [1]) 1 2) (if (seq ()) 1 2)]":    "[1 1 2]",
+		"(take 3 (cons :a (range)))":                             "(:a 0 1)",
+		"(do (def ones (lazy-seq (cons 1 ones))) (take 2 ones))": "(1 1)",
+	}
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	checkEval(t, in, tests)
+}
+
+func TestLazySeqRealizedOnce(t *testing.T) {
+	var out bytes.Buffer
+	in := NewInterpreter(&out, &bytes.Buffer{})
+	res, err := in.Eval(`
+(def s (map (fn [x] (println x) x) [1 2 3]))
+(println "defined")
+(head s)
+(take 2 s)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in.Show(res); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "defined\n1\n2\n" {
+		t.Errorf("expected the elements to be realized on demand and once, got %q", out.String())
+	}
+
+	res, err = in.Eval("(map (fn [x] (throw (ex-info \"boom\" {}))) [1])")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in.Show(res); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("expected realizing to fail, got %v", err)
+	}
+}
@@ -1,9 +1,11 @@
-package main
+package clojura
 
 import (
 	"bufio"
-	"errors"
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -16,8 +18,13 @@ const (
 	CommentStart = ';'
 	Space        = ' '
 	Tick         = '\''
+	Backtick     = '`'
+	Tilde        = '~'
+	Hash         = '#'
 	Newline      = '\n'
 	Tab          = '\t'
+	Return       = '\r'
+	Comma        = ','
 )
 
 var separators = map[rune]bool{
@@ -30,6 +37,8 @@ var separators = map[rune]bool{
 	Space:        true,
 	Tab:          true,
 	Newline:      true,
+	Return:       true,
+	Comma:        true,
 }
 
 var tokens = map[rune]bool{
@@ -41,63 +50,158 @@ var tokens = map[rune]bool{
 	LCurlyBrace:  true,
 }
 
+// Pos is a position in the source code.
+type Pos struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Pos) String() string {
+	file := p.File
+	if file == "" {
+		file = "NO_SOURCE_FILE"
+	}
+	return fmt.Sprintf("%s:%d:%d", file, p.Line, p.Column)
+}
+
+type TokenKind uint8
+
+const (
+	TokenAtom TokenKind = iota
+	TokenString
+)
+
+// Token is a piece of source text along with the position it starts at. The
+// text of a string token holds its contents with escapes already resolved.
+type Token struct {
+	Text string
+	Pos  Pos
+	Kind TokenKind
+}
+
 type Lexer struct {
 	reader *bufio.Reader
+	pos    Pos
+	prev   Pos
 }
 
 func NewLexer(r io.Reader) *Lexer {
+	return NewFileLexer(r, "")
+}
+
+// NewFileLexer creates a lexer that reports positions in the named file.
+func NewFileLexer(r io.Reader, file string) *Lexer {
 	return &Lexer{
 		reader: bufio.NewReader(r),
+		pos:    Pos{File: file, Line: 1, Column: 1},
 	}
 }
 
-func (l *Lexer) ReadToken() (string, error) {
-	r, _, err := l.reader.ReadRune()
+func (l *Lexer) ReadToken() (Token, error) {
+	r, err := l.readRune()
 	if err != nil {
-		return "", err
+		return Token{}, err
 	}
 	for {
+		start := l.prev
 		if isToken(r) {
-			return string(r), nil
+			return Token{Text: string(r), Pos: start}, nil
+		} else if r == Tick || r == Backtick {
+			return Token{Text: string(r), Pos: start}, nil
+		} else if r == Tilde {
+			return Token{Text: l.readUnquote(), Pos: start}, nil
+		} else if r == Hash && l.peek(LCurlyBrace) {
+			return Token{Text: "#{", Pos: start}, nil
 		} else if isWhitespace(r) {
 			r, err = l.drainWhitespace()
 			if err != nil {
-				return "", err
+				return Token{}, err
 			}
 			continue
 		} else if r == '"' {
-			s, err := l.readWhile('"')
+			s, err := l.readString()
 			if err != nil {
-				return "", err
+				return Token{}, err
 			}
-			return "\"" + s + "\"", err
+			return Token{Text: s, Pos: start, Kind: TokenString}, nil
 		} else if r == ';' {
 			r, err = l.drainWhile('\n')
 			if err != nil {
-				return "", err
+				return Token{}, err
 			}
 			continue
 		} else {
 			token, err := l.readToken()
 			if err != nil {
-				return "", err
+				return Token{}, err
 			}
-			return string(r) + token, nil
+			return Token{Text: string(r) + token, Pos: start}, nil
 		}
 	}
+}
 
-	return "", errors.New("how did we get here?")
+// readRune reads the next rune and advances the current position.
+func (l *Lexer) readRune() (rune, error) {
+	r, _, err := l.reader.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	l.prev = l.pos
+	if r == Newline {
+		l.pos.Line++
+		l.pos.Column = 1
+	} else {
+		l.pos.Column++
+	}
+	return r, nil
+}
+
+// unreadRune steps back over the last rune read by readRune.
+func (l *Lexer) unreadRune() {
+	l.reader.UnreadRune()
+	l.pos = l.prev
 }
 
+// peek reads the next rune if it is r and reports whether it was.
+func (l *Lexer) peek(r rune) bool {
+	next, err := l.readRune()
+	if err != nil {
+		return false
+	}
+	if next != r {
+		l.unreadRune()
+		return false
+	}
+	return true
+}
+
+// readUnquote reads the rest of an unquote token, which is either ~ or ~@.
+func (l *Lexer) readUnquote() string {
+	r, err := l.readRune()
+	if err != nil {
+		return "~"
+	}
+	if r != '@' {
+		l.unreadRune()
+		return "~"
+	}
+	return "~@"
+}
+
+// readToken reads the rest of an atom, which ends at a separator or at the
+// end of the input.
 func (l *Lexer) readToken() (string, error) {
-	defer l.reader.UnreadRune()
 	var res string
 	for {
-		r, _, err := l.reader.ReadRune()
-		if err != nil {
+		r, err := l.readRune()
+		if err == io.EOF {
+			break
+		} else if err != nil {
 			return "", err
 		}
 		if isSeparator(r) {
+			l.unreadRune()
 			break
 		}
 		res += string(r)
@@ -105,24 +209,77 @@ func (l *Lexer) readToken() (string, error) {
 	return res, nil
 }
 
-func (l *Lexer) readWhile(cr rune) (string, error) {
-	var res string
+// readString reads the rest of a string literal, resolving escape sequences.
+func (l *Lexer) readString() (string, error) {
+	var res strings.Builder
 	for {
-		r, _, err := l.reader.ReadRune()
-		if err != nil {
+		r, err := l.readRune()
+		if err == io.EOF {
+			return "", fmt.Errorf("%s: EOF while reading string", l.pos)
+		} else if err != nil {
 			return "", err
 		}
-		if r == cr {
+		if r == '"' {
 			break
 		}
-		res += string(r)
+		if r == '\\' {
+			r, err = l.readEscape()
+			if err != nil {
+				return "", err
+			}
+		}
+		res.WriteRune(r)
 	}
-	return res, nil
+	return res.String(), nil
+}
+
+// readEscape reads the character following a backslash in a string literal.
+// Errors are reported at the position of the backslash.
+func (l *Lexer) readEscape() (rune, error) {
+	start := l.prev
+	r, err := l.readRune()
+	if err == io.EOF {
+		return 0, fmt.Errorf("%s: EOF while reading string", l.pos)
+	} else if err != nil {
+		return 0, err
+	}
+	switch r {
+	case 'n':
+		return '\n', nil
+	case 't':
+		return '\t', nil
+	case 'r':
+		return '\r', nil
+	case '"', '\\':
+		return r, nil
+	case 'u':
+		// A unicode escape takes exactly 4 hexadecimal digits.
+		var code []rune
+		for len(code) < 4 {
+			c, err := l.readRune()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return 0, err
+			}
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				l.unreadRune()
+				break
+			}
+			code = append(code, c)
+		}
+		n, err := strconv.ParseUint(string(code), 16, 16)
+		if len(code) < 4 || err != nil {
+			return 0, fmt.Errorf("%s: invalid unicode escape \\u%s", start, string(code))
+		}
+		return rune(n), nil
+	}
+	return 0, fmt.Errorf("%s: unsupported escape character \\%c", start, r)
 }
 
 func (l *Lexer) drainWhile(t rune) (r rune, err error) {
 	for {
-		r, _, err = l.reader.ReadRune()
+		r, err = l.readRune()
 		if err != nil {
 			return 0, err
 		}
@@ -135,7 +292,7 @@ func (l *Lexer) drainWhile(t rune) (r rune, err error) {
 
 func (l *Lexer) drainWhitespace() (r rune, err error) {
 	for {
-		r, _, err = l.reader.ReadRune()
+		r, err = l.readRune()
 		if err != nil {
 			return 0, err
 		}
@@ -155,7 +312,8 @@ func isSeparator(r rune) bool {
 }
 
 func isWhitespace(r rune) bool {
-	if r == ' ' || r == '\n' || r == '\t' {
+	// Commas are whitespace, so they can separate the entries of maps.
+	if r == Space || r == Newline || r == Tab || r == Return || r == Comma {
 		return true
 	}
 	return false
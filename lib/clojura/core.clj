@@ -0,0 +1,68 @@
+(ns clojura.core
+  "The core library, loaded by every interpreter before it evaluates code.")
+
+(def inc (fn
+  (n)
+  (+ n 1)))
+
+(def dec (fn
+  (n)
+  (- n 1)))
+
+(defn identity
+  "Returns its argument."
+  [x]
+  x)
+
+;; when evaluates body when test is truthy, and returns nil otherwise.
+(defmacro when [test & body]
+  `(if ~test (do ~@body)))
+
+(defmacro lazy-seq [& body]
+  `(lazy-seq* (fn [] ~@body)))
+
+(defn each
+  "Calls f on every element of coll, for its side effects."
+  [f coll]
+  (let [s (seq coll)]
+    (if s
+      (do (f (first s))
+          (recur f (rest s))))))
+
+(defn filter
+  "Returns a lazy sequence of the elements of coll for which pred holds."
+  [pred coll]
+  (lazy-seq
+    (let [s (seq coll)]
+      (if s
+        (if (pred (first s))
+          (cons (first s) (filter pred (rest s)))
+          (filter pred (rest s)))))))
+
+(defn map
+  "Returns a lazy sequence of f applied to every element of coll."
+  [f coll]
+  (lazy-seq
+    (let [s (seq coll)]
+      (if s
+        (cons (f (first s)) (map f (rest s)))))))
+
+(defn reduce
+  "Combines the elements of coll with f, from left to right, starting with
+  init, or with the first element when no init is given. Reducing no
+  elements without init returns (f)."
+  ([f coll]
+    (let [s (seq coll)]
+      (if s
+        (reduce f (first s) (rest s))
+        (f))))
+  ([f init coll]
+    (let [s (seq coll)]
+      (if s
+        (recur f (f init (first s)) (rest s))
+        init))))
+
+(defn empty?
+  "Tells whether coll has no elements."
+  [coll]
+  (not (seq coll)))
@@ -0,0 +1,40 @@
+(ns clojura.set
+  "Operations on sets.")
+
+(defn union
+  "Returns the set of the elements of every given set."
+  [& sets]
+  (reduce (fn [res s] (reduce conj res (seq s))) #{} sets))
+
+(defn intersection
+  "Returns the set of the elements of s found in every other given set."
+  [s & sets]
+  (reduce (fn [res other]
+            (reduce (fn [r x] (if (contains? other x) r (disj r x))) res (seq res)))
+          s sets))
+
+(defn difference
+  "Returns the set of the elements of s found in none of the other given
+  sets."
+  [s & sets]
+  (reduce (fn [res other] (reduce disj res (seq other))) s sets))
+
+(defn subset?
+  "Tells whether every element of a is an element of b."
+  [a b]
+  (reduce (fn [res x] (and res (contains? b x))) true (seq a)))
+
+(defn superset?
+  "Tells whether every element of b is an element of a."
+  [a b]
+  (subset? b a))
+
+(defn select
+  "Returns the set of the elements of s for which pred holds."
+  [pred s]
+  (reduce (fn [res x] (if (pred x) res (disj res x))) s (seq s)))
+
+(defn map-invert
+  "Returns m with its keys and values swapped."
+  [m]
+  (reduce (fn [res [k v]] (assoc res v k)) {} (seq m)))
@@ -0,0 +1,16 @@
+(ns clojura.string
+  "Functions on strings. The ones not defined here are builtins.")
+
+(defn join
+  "Returns the elements of coll as strings, separated by sep."
+  ([coll] (join "" coll))
+  ([sep coll]
+   (let [items (seq coll)]
+     (if items
+       (reduce (fn [res x] (str res sep x)) (str (first items)) (rest items))
+       ""))))
+
+(defn split-lines
+  "Splits s on newlines."
+  [s]
+  (split s "\n"))
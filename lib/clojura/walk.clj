@@ -0,0 +1,66 @@
+(ns clojura.walk
+  "Generic traversal of nested data structures.")
+
+(defn- walk-items
+  "Returns a vector of f applied to every element of coll, in order."
+  [f coll]
+  (reduce (fn [res x] (conj res (f x))) [] (seq coll)))
+
+(defn walk
+  "Applies inner to every element of form, builds a collection of the same
+  kind from the results and returns outer applied to it. Map entries are
+  walked as [key value] vectors."
+  [inner outer form]
+  (if (list? form)
+    (outer (or (seq (walk-items inner form)) '()))
+    (if (vector? form)
+      (outer (walk-items inner form))
+      (if (map? form)
+        (outer (reduce (fn [res e]
+                         (let [[k v] (inner e)]
+                           (assoc res k v)))
+                       {} (seq form)))
+        (if (set? form)
+          (outer (set (walk-items inner form)))
+          (outer form))))))
+
+(defn postwalk
+  "Walks form depth first, replacing every subform with f applied to it
+  once its own subforms are replaced."
+  [f form]
+  (walk (fn [x] (postwalk f x)) f form))
+
+(defn prewalk
+  "Walks form depth first, replacing every subform with f applied to it
+  before its own subforms are walked."
+  [f form]
+  (walk (fn [x] (prewalk f x)) (fn [x] x) (f form)))
+
+(defn- update-keys
+  "Returns m with f applied to its keys if it is a map."
+  [f m]
+  (if (map? m)
+    (reduce (fn [res [k v]] (assoc res (f k) v)) {} (seq m))
+    m))
+
+(defn keywordize-keys
+  "Turns the string keys of every map nested in m into keywords."
+  [m]
+  (postwalk (fn [x] (update-keys (fn [k] (if (string? k) (keyword k) k)) x)) m))
+
+(defn stringify-keys
+  "Turns the keyword keys of every map nested in m into strings."
+  [m]
+  (postwalk (fn [x] (update-keys (fn [k] (if (keyword? k) (name k) k)) x)) m))
+
+(defn postwalk-replace
+  "Replaces the subforms of form that are keys of smap with their values,
+  from the leaves up."
+  [smap form]
+  (postwalk (fn [x] (if (contains? smap x) (get smap x) x)) form))
+
+(defn prewalk-replace
+  "Replaces the subforms of form that are keys of smap with their values,
+  from the root down."
+  [smap form]
+  (prewalk (fn [x] (if (contains? smap x) (get smap x) x)) form))
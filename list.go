@@ -1,166 +1,137 @@
-package main
+package clojura
 
 import (
-	"fmt"
+	"errors"
+	"strings"
 )
 
-type Lister interface {
-	Add(Sexpr) *List
-	Head() Sexpr
-	GetTail() *List
-	Length() int
-	Conj(*List) *List
-}
-
-type Node struct {
-	Next *Node
-	Val  Sexpr
-}
-
+// List is a persistent singly linked list. A list is never changed once it
+// is built: adding an element in front of it makes a new list sharing the
+// old one as its rest, so cons and rest take constant time. The parser
+// reads list forms into an Expression, which is filled in as elements are
+// read.
 type List struct {
-	Node *Node
-	Len  int
-	Tail *Node
+	first Sexpr
+	rest  *List
+	count int
 }
 
+// emptyList is the list without elements, which every list ends with.
+var emptyList = &List{}
+
+// NewList returns the empty list.
 func NewList() *List {
-	return &List{
-		Node: nil,
-		Len:  0,
-		Tail: nil,
-	}
+	return emptyList
 }
 
-func (l *List) Copy() *List {
-	if l.Node == nil {
-		cl := *l
-		return &cl
-	}
-	n := l.Node
-	i := *l.Node
-	v := &i
-	for n.Next != nil {
-		n = n.Next
-		c := *n
-		v.Next = &c
-		v = &c
-	}
-	return &List{
-		Len:  l.Len,
-		Node: &i,
-		Tail: v,
+// listOf creates a list holding items in order.
+func listOf(items []Sexpr) *List {
+	res := emptyList
+	for i := len(items) - 1; i >= 0; i-- {
+		res = res.Add(items[i])
 	}
+	return res
 }
 
-func (l *List) Conj(a *List) *List {
-	lc := l.Copy()
-	ac := a.Copy()
-
-	if lc.Node == nil {
-		return ac
-	} else if ac.Node == nil {
-		return lc
-	}
-	lc.Tail.Next = ac.Node
-	lc.Len += ac.Len
-	lc.Tail = ac.Tail
-	return lc
+// Add returns a list with s in front of the elements of l.
+func (l *List) Add(s Sexpr) *List {
+	return &List{first: s, rest: l, count: l.count + 1}
 }
 
-func (l *List) addFast(s Sexpr) {
-	node := &Node{
-		Next: l.Node,
-		Val:  s,
+// conjAll returns l with items added in front of it one by one, so the last
+// one comes first.
+func (l *List) conjAll(items []Sexpr) *List {
+	for _, item := range items {
+		l = l.Add(item)
 	}
-	l.Node = node
-	l.Len += 1
+	return l
 }
 
-func (l *List) Add(s Sexpr) *List {
-	node := &Node{
-		Next: l.Node,
-		Val:  s,
-	}
-	tail := l.Tail
-	if l.Len == 0 {
-		tail = node
+// Concat returns a list holding the elements of l followed by those of a.
+// The elements of l are copied, which takes linear time, and a is shared.
+func (l *List) Concat(a *List) *List {
+	items := make([]Sexpr, 0, l.count)
+	for n := l; n.count > 0; n = n.rest {
+		items = append(items, n.first)
 	}
-	return &List{
-		Node: node,
-		Len:  l.Len + 1,
-		Tail: tail,
+	res := a
+	for i := len(items) - 1; i >= 0; i-- {
+		res = res.Add(items[i])
 	}
+	return res
 }
 
 func (l *List) Head() Sexpr {
-	if l.Node != nil {
-		return l.Node.Val
+	if l.count == 0 {
+		return Nil
 	}
-	return nil
+	return l.first
 }
+
 func (l *List) GetTail() *List {
-	if l.Node != nil {
-		return &List{
-			Node: l.Node.Next,
-			Len:  l.Len - 1,
-			Tail: l.Tail,
-		}
+	if l.count == 0 {
+		return l
+	}
+	return l.rest
+}
+
+func (l *List) Length() int {
+	return l.count
+}
+
+// Slice returns the elements of the list.
+func (l *List) Slice() []Sexpr {
+	res := make([]Sexpr, 0, l.count)
+	for n := l; n.count > 0; n = n.rest {
+		res = append(res, n.first)
 	}
+	return res
+}
 
-	return &List{
-		Node: nil,
-		Len:  0,
-		Tail: nil,
+func (l *List) Seq() Seq {
+	if l.count == 0 {
+		return nil
 	}
+	return l
 }
-func (l *List) Length() int {
-	return l.Len
+
+func (l *List) First() Sexpr {
+	return l.Head()
+}
+
+func (l *List) Rest() Seq {
+	return l.GetTail()
 }
 
 func (l *List) Type() CoreType {
 	return TypeList
 }
+
 func (l *List) Append(s Sexpr) error {
-	c := *l
-	n := (&c).Add(s)
-	*l = *n
-	return nil
+	return errors.New("cannot append")
 }
+
 func (l *List) String() string {
-	res := "'("
-	n := l.Node
-	if n != nil {
-		res += fmt.Sprintf("%s ", n.Val)
-		for n.Next != nil {
-			n = n.Next
-			res += fmt.Sprintf("%s ", n.Val)
-		}
+	return l.format(readableString)
+}
+
+// format prints the list, showing every element with show.
+func (l *List) format(show func(Sexpr) string) string {
+	res := make([]string, 0, l.count)
+	for n := l; n.count > 0; n = n.rest {
+		res = append(res, show(n.first))
 	}
-	res += ")"
-	return res
+	return "(" + strings.Join(res, " ") + ")"
 }
 
-// TODO: MAKE IMMUTABLE
+// Eval returns the list itself: a list is data, such as the value of a
+// quoted form, while code is read into expressions.
 func (l *List) Eval(c *Context) Sexpr {
-	l = l.Copy()
-	n := l.Node
-	if n != nil {
-		if n.Val != nil {
-			n.Val = n.Val.Eval(c)
-		}
-
-		for n.Next != nil {
-			n = n.Next
-			if n.Val != nil {
-				n.Val = n.Val.Eval(c)
-			}
-		}
-	}
 	return l
 }
+
+// Bool returns true: only nil and false are falsey, even an empty list is
+// truthy.
 func (l *List) Bool() bool {
-	if l.Length() > 0 {
-		return true
-	}
-	return false
+	return true
 }
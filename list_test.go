@@ -1,28 +1,75 @@
-package main
+package clojura
 
 import (
+	"bytes"
 	"testing"
 )
 
-// func TestListAppend(t *testing.T) {
-// 	list := &list{val: &number{1}}
-// 	list.Append(&number{2})
-//
-// 	t.Log(list)
-// 	t.Log(list.next)
-// }
-//
-// func TestListCopy(t *testing.T) {
-// 	list := NewList(&number{1})
-// 	list = list.Add(&number{2})
-//
-// 	list2 := list.Copy()
-//
-// 	list.next.val = &number{100}
-//
-// 	t.Log(list)
-// 	t.Log(list2)
-// }
+func TestListPersistent(t *testing.T) {
+	l := listOf([]Sexpr{Number(2), Number(3)})
+	l1 := l.Add(Number(1))
+	l0 := l.Add(Number(0))
+	if l1.String() != "(1 2 3)" || l0.String() != "(0 2 3)" || l.String() != "(2 3)" {
+		t.Errorf("unexpected lists %s %s %s", l1, l0, l)
+	}
+	if l1.GetTail() != l || l0.GetTail() != l {
+		t.Error("expected the rest of a list to be shared")
+	}
+	if l.Append(Number(4)) == nil || l.Length() != 2 {
+		t.Error("expected lists not to be appended to")
+	}
+	if c := l.Concat(l1); c.String() != "(2 3 1 2 3)" || c.GetTail().GetTail() != l1 {
+		t.Errorf("expected concat to share its last list, got %s", c)
+	}
+	if NewList().GetTail().Length() != 0 || NewList().Head() != Nil {
+		t.Error("expected the rest of the empty list to be empty")
+	}
+}
+
+func TestConj(t *testing.T) {
+	tests := map[string]string{
+		"(conj '(1 2) 3)":       "(3 1 2)",
+		"(conj '(1 2) 3 4)":     "(4 3 1 2)",
+		"(conj nil 1)":          "(1)",
+		"(conj '() '(1))":       "((1))",
+		"(conj [1] 2 3)":        "[1 2 3]",
+		"(conj (range 1 3) 0)":  "(0 1 2)",
+		"(conj {:a 1} [:b 2])":  "{:a 1, :b 2}",
+		"(conj)":                "[]",
+		"(list? (conj '(1) 0))": "true",
+	}
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	checkEval(t, in, tests)
+	l, err := in.Eval("(def l '(1 2)) l")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := in.Eval("(conj l 0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.(*List).GetTail() != l {
+		t.Error("expected conj to share the list it adds to")
+	}
+}
+
+func TestQuotedListNotCopied(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	if _, err := in.Eval("(defn data [] '(1 (+ 1 1)))"); err != nil {
+		t.Fatal(err)
+	}
+	a, err := in.Eval("(data)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := in.Eval("(data)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b || a.String() != "(1 (+ 1 1))" {
+		t.Errorf("expected quoted data to evaluate to itself, got %s and %s", a, b)
+	}
+}
 
 func BenchmarkCoreType(b *testing.B) {
 	var s Sexpr = Number(1)
@@ -51,3 +98,35 @@ func BenchmarkCustomType(b *testing.B) {
 		}
 	}
 }
+
+// evalBenchmarks are programs run by both the tree walker and the VM.
+var evalBenchmarks = []struct {
+	name string
+	src  string
+}{
+	{"loop", "(loop [i 0 acc 0] (if (< i 10000) (recur (+ i 1) (+ acc i)) acc))"},
+	{"fib", "(do (def fib (fn [n] (if (< n 2) n (+ (fib (- n 1)) (fib (- n 2)))))) (fib 15))"},
+	{"closures", "(let [add (fn [a] (fn [b] (+ a b)))] (loop [i 0 acc 0] (if (< i 1000) (recur (+ i 1) ((add i) acc)) acc)))"},
+}
+
+func benchmarkEval(b *testing.B, vm bool) {
+	for _, bm := range evalBenchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+			in.UseVM(vm)
+			for i := 0; i < b.N; i++ {
+				if _, err := in.Eval(bm.src); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkTreeWalker(b *testing.B) {
+	benchmarkEval(b, false)
+}
+
+func BenchmarkVM(b *testing.B) {
+	benchmarkEval(b, true)
+}
@@ -1,6 +1,7 @@
-package main
+package clojura
 
 import (
+	"io"
 	slog "log"
 )
 
@@ -12,15 +13,21 @@ const (
 )
 
 type Logger struct {
-	level int
+	level  int
+	logger *slog.Logger
 }
 
-func NewLogger(level int) *Logger {
+func NewLogger(level int, w io.Writer) *Logger {
 	return &Logger{
-		level: level,
+		level:  level,
+		logger: slog.New(w, "", slog.LstdFlags),
 	}
 }
 
+func (l *Logger) SetLevel(level int) {
+	l.level = level
+}
+
 func (l Logger) Debug(args ...interface{}) {
 	l.print(Debug, args...)
 }
@@ -43,12 +50,12 @@ func (l Logger) Errorf(format string, args ...interface{}) {
 
 func (l *Logger) print(level int, args ...interface{}) {
 	if l.level >= level {
-		slog.Println(args...)
+		l.logger.Println(args...)
 	}
 }
 
 func (l *Logger) printf(level int, format string, args ...interface{}) {
 	if l.level >= level {
-		slog.Printf(format, args...)
+		l.logger.Printf(format, args...)
 	}
 }
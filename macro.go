@@ -0,0 +1,347 @@
+package clojura
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// macro is a macro defined with defmacro. Its expander gets the forms it is
+// called with as data and returns the form to evaluate in their place.
+type macro struct {
+	fn *function
+}
+
+func (m *macro) Type() CoreType {
+	return TypeMacros
+}
+
+func (m *macro) Bool() bool {
+	return true
+}
+
+func (m *macro) String() string {
+	return "macro " + m.fn.name
+}
+
+func (m *macro) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (m *macro) Eval(c *Context) Sexpr {
+	return m
+}
+
+// Create expands and evaluates a call to a macro which was not expanded by
+// the analyzer, such as a call through a local. The expansion can only see
+// globals.
+func (m *macro) Create(c *Context, args []Sexpr) Sexpr {
+	in := c.interp
+	return inTail(c, in.analyze(m.expandCall(in, args[1:], Pos{})))
+}
+
+// expandCall expands a call to the macro written at pos into the form to
+// analyze in its place.
+func (m *macro) expandCall(in *Interpreter, args []Sexpr, pos Pos) Sexpr {
+	data := make([]Sexpr, len(args))
+	for i, arg := range args {
+		data[i] = formToData(arg)
+	}
+	in.pushFrame(Frame{Name: m.fn.name, Pos: pos})
+	res := m.expand(data)
+	in.popFrame()
+	return dataToForm(res, pos)
+}
+
+// expand calls the expander with args and returns the expansion as data.
+func (m *macro) expand(args []Sexpr) Sexpr {
+	return orNil(m.fn.Call(args))
+}
+
+// formToData turns a parsed form into the data a macro works with: calls
+// become lists and collections hold data as well.
+func formToData(s Sexpr) Sexpr {
+	switch t := s.(type) {
+	case *Expression:
+		return listOf(mapForms(t.Elements, formToData))
+	case *List:
+		return listOf(mapForms(sequentialItems(t), formToData))
+	case *Vector:
+		return NewVector(mapForms(t.Slice(), formToData)...)
+	case *HashMap:
+		res := emptyMap
+		t.Each(func(key, val Sexpr) {
+			res = res.Assoc(formToData(key), formToData(val))
+		})
+		return res
+	case *Set:
+		return NewSet(mapForms(t.Slice(), formToData)...)
+	}
+	return s
+}
+
+// dataToForm turns data returned by a macro back into a form that can be
+// evaluated. Lists become calls attributed to pos.
+func dataToForm(s Sexpr, pos Pos) Sexpr {
+	conv := func(s Sexpr) Sexpr {
+		return dataToForm(s, pos)
+	}
+	switch t := s.(type) {
+	case *List:
+		return &Expression{Elements: mapForms(sequentialItems(t), conv), Pos: pos}
+	case *Vector:
+		return NewVector(mapForms(t.Slice(), conv)...)
+	case *HashMap:
+		res := emptyMap
+		t.Each(func(key, val Sexpr) {
+			res = res.Assoc(conv(key), conv(val))
+		})
+		return res
+	case *Set:
+		return NewSet(mapForms(t.Slice(), conv)...)
+	}
+	return s
+}
+
+func mapForms(forms []Sexpr, f func(Sexpr) Sexpr) []Sexpr {
+	res := make([]Sexpr, len(forms))
+	for i, form := range forms {
+		res[i] = f(form)
+	}
+	return res
+}
+
+// defmacroNode defines a macro whose expander is the function it holds.
+type defmacroNode struct {
+	node
+	v  *Var
+	fn *fnNode
+}
+
+func (d *defmacroNode) Eval(c *Context) Sexpr {
+	m := &macro{fn: d.fn.Eval(c).(*function)}
+	d.v.value = m
+	return m
+}
+
+// analyzeDefmacro analyzes (defmacro name [params] body*), which takes the
+// same arities as fn.
+func (a *analyzer) analyzeDefmacro(e *Expression) Sexpr {
+	if len(e.Elements) < 4 {
+		a.errorf("defmacro should have a name, an argument list and a body")
+	}
+	n, ok := e.Elements[1].(Literal)
+	if !ok {
+		a.errorf("defmacro name should be a literal, got %s", e.Elements[1])
+	}
+	v := a.in.ns.intern(n)
+	fn := &Expression{Elements: append([]Sexpr{Literal("fn")}, e.Elements[1:]...), Pos: e.Pos}
+	return &defmacroNode{node: node{e}, v: v, fn: a.analyzeFn(fn)}
+}
+
+// isCall reports whether s is a call form whose head is the symbol name.
+func isCall(s Sexpr, name Literal) (*Expression, bool) {
+	e, ok := s.(*Expression)
+	if !ok || len(e.Elements) == 0 || e.Elements[0] != name {
+		return nil, false
+	}
+	if len(e.Elements) != 2 {
+		throwf("Wrong number of args (%d) passed to %s", len(e.Elements)-1, name)
+	}
+	return e, true
+}
+
+// syntaxQuoteNode builds the data described by a syntax-quoted template.
+type syntaxQuoteNode struct {
+	node
+	template Sexpr
+}
+
+func (q *syntaxQuoteNode) Eval(c *Context) Sexpr {
+	return syntaxQuote(c, q.template, map[Literal]Literal{})
+}
+
+// template analyzes the forms unquoted in a syntax-quote template and keeps
+// the rest as it is. Symbols naming vars outside of clojura.core are
+// qualified, so that a macro expands to the same vars in any namespace.
+func (a *analyzer) template(form Sexpr) Sexpr {
+	switch t := form.(type) {
+	case Literal:
+		if v, _ := a.in.lookupVar(t); v != nil && v.ns != a.in.core {
+			return v.ns.name + "/" + v.name
+		}
+	case *Expression:
+		if len(t.Elements) > 0 {
+			if head := t.Elements[0]; head == Literal("unquote") || head == Literal("unquote-splicing") {
+				a.checkArity(string(head.(Literal)), t.Elements[1:], 1)
+				return &Expression{Elements: []Sexpr{head, a.analyze(t.Elements[1], false)}, Pos: t.Pos}
+			}
+		}
+		return &Expression{Elements: mapForms(t.Elements, a.template), Pos: t.Pos}
+	case *List:
+		return listOf(mapForms(sequentialItems(t), a.template))
+	case *Vector:
+		return NewVector(mapForms(t.Slice(), a.template)...)
+	case *HashMap:
+		res := emptyMap
+		t.Each(func(key, val Sexpr) {
+			res = res.Assoc(a.template(key), a.template(val))
+		})
+		return res
+	case *Set:
+		return NewSet(mapForms(t.Slice(), a.template)...)
+	}
+	return form
+}
+
+// syntaxQuote builds the data described by a syntax-quoted template,
+// evaluating the unquoted parts. Symbols ending with # are replaced with
+// generated ones, the same symbol for every use within the template.
+func syntaxQuote(c *Context, form Sexpr, gensyms map[Literal]Literal) Sexpr {
+	switch t := form.(type) {
+	case Literal:
+		if len(t) > 1 && strings.HasSuffix(string(t), "#") {
+			sym, ok := gensyms[t]
+			if !ok {
+				sym = gensym(string(t[:len(t)-1])+"__") + "__auto__"
+				gensyms[t] = sym
+			}
+			return sym
+		}
+		return t
+	case *Expression:
+		if e, ok := isCall(t, "unquote"); ok {
+			return e.Elements[1].Eval(c)
+		}
+		if _, ok := isCall(t, "unquote-splicing"); ok {
+			throwf("unquote-splicing used outside of a list")
+		}
+		return listOf(syntaxQuoteItems(c, t.Elements, gensyms))
+	case *List:
+		return listOf(syntaxQuoteItems(c, sequentialItems(t), gensyms))
+	case *Vector:
+		return NewVector(syntaxQuoteItems(c, t.Slice(), gensyms)...)
+	case *HashMap:
+		res := emptyMap
+		t.Each(func(key, val Sexpr) {
+			res = res.Assoc(syntaxQuote(c, key, gensyms), syntaxQuote(c, val, gensyms))
+		})
+		return res
+	case *Set:
+		return NewSet(syntaxQuoteItems(c, t.Slice(), gensyms)...)
+	}
+	return form
+}
+
+// syntaxQuoteItems builds the elements of a syntax-quoted collection,
+// splicing in the values of unquote-splicing forms.
+func syntaxQuoteItems(c *Context, forms []Sexpr, gensyms map[Literal]Literal) []Sexpr {
+	res := make([]Sexpr, 0, len(forms))
+	for _, form := range forms {
+		if e, ok := isCall(form, "unquote-splicing"); ok {
+			val := e.Elements[1].Eval(c)
+			if val == Nil {
+				continue
+			}
+			if !isSequential(val) {
+				throwf("unquote-splicing argument should be a list or a vector, got %s", val)
+			}
+			res = append(res, sequentialItems(val)...)
+			continue
+		}
+		res = append(res, syntaxQuote(c, form, gensyms))
+	}
+	return res
+}
+
+var gensymCounter int64
+
+// gensym returns a new symbol starting with prefix.
+func gensym(prefix string) Literal {
+	return Literal(fmt.Sprintf("%s%d", prefix, atomic.AddInt64(&gensymCounter, 1)))
+}
+
+func coreGensym(args []Sexpr) Sexpr {
+	if len(args) > 1 {
+		throwf("Wrong number of args (%d) passed to gensym", len(args))
+	}
+	prefix := "G__"
+	if len(args) == 1 {
+		prefix = printString(args[0])
+	}
+	return gensym(prefix)
+}
+
+// expandOnce expands form if it is a call to a macro defined with defmacro
+// and reports whether it did.
+func expandOnce(c *Context, form Sexpr) (Sexpr, bool) {
+	l, ok := form.(*List)
+	if !ok || l.Length() == 0 {
+		return form, false
+	}
+	name, ok := l.Head().(Literal)
+	if !ok {
+		return form, false
+	}
+	val, _ := c.Get(name)
+	m, ok := val.(*macro)
+	if !ok {
+		return form, false
+	}
+	return m.expand(sequentialItems(l.GetTail())), true
+}
+
+// expand expands form until it is no longer a macro call.
+func expand(c *Context, form Sexpr) Sexpr {
+	for {
+		res, ok := expandOnce(c, form)
+		if !ok {
+			return res
+		}
+		form = res
+	}
+}
+
+// expandAll expands form and all of its subforms, leaving quoted forms as
+// they are.
+func expandAll(c *Context, form Sexpr) Sexpr {
+	form = expand(c, form)
+	each := func(s Sexpr) Sexpr {
+		return expandAll(c, s)
+	}
+	switch t := form.(type) {
+	case *List:
+		if head := t.Head(); head == Literal("quote") || head == Literal("syntax-quote") {
+			return t
+		}
+		return listOf(mapForms(sequentialItems(t), each))
+	case *Vector:
+		return NewVector(mapForms(t.Slice(), each)...)
+	case *HashMap:
+		res := emptyMap
+		t.Each(func(key, val Sexpr) {
+			res = res.Assoc(each(key), each(val))
+		})
+		return res
+	case *Set:
+		return NewSet(mapForms(t.Slice(), each)...)
+	}
+	return form
+}
+
+func macroexpand1Macro(c *Context, args []Sexpr) Sexpr {
+	checkArity("macroexpand-1", args[1:], 1)
+	res, _ := expandOnce(c, args[1].Eval(c))
+	return res
+}
+
+func macroexpandMacro(c *Context, args []Sexpr) Sexpr {
+	checkArity("macroexpand", args[1:], 1)
+	return expand(c, args[1].Eval(c))
+}
+
+func macroexpandAllMacro(c *Context, args []Sexpr) Sexpr {
+	checkArity("macroexpand-all", args[1:], 1)
+	return expandAll(c, args[1].Eval(c))
+}
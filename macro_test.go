@@ -0,0 +1,66 @@
+package clojura
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMacros(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	_, err := in.Eval(`
+(defmacro unless [c body] ` + "`" + `(if ~c nil ~body))
+(defmacro when-not [c body] ` + "`" + `(unless ~c ~body))
+(defmacro add-all [xs] ` + "`" + `(+ ~@xs))
+(defmacro twice [x] ` + "`" + `(let [v# ~x] (+ v# v#)))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"(unless false 1)":            "1",
+		"(unless true 1)":             "nil",
+		"(add-all [1 2 3])":           "6",
+		"(twice 21)":                  "42",
+		"`[a ~(+ 1 2) {:b ~(* 2 2)}]": "[a 3 {:b 4}]",
+		"(= (macroexpand-1 `(when-not x 1)) `(unless x 1))":           "true",
+		"(= (macroexpand `(when-not x 1)) `(if x nil 1))":             "true",
+		"(= (macroexpand-all `(do (unless x 1))) `(do (if x nil 1)))": "true",
+	}
+	checkEval(t, in, tests)
+	if _, err := in.Eval("~x"); err == nil || !strings.Contains(err.Error(), "unquote used outside of syntax-quote") {
+		t.Errorf("expected an unquote error, got %v", err)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	tests := map[string]string{
+		"(do 'foo)":             "foo",
+		"(do '42)":              "42",
+		"(quote x)":             "x",
+		"(do ''x)":              "(quote x)",
+		"'(1 (a \"b\") [c :d])": `(1 (a "b") [c :d])`,
+		"'{:a (b c)}":           "{:a (b c)}",
+		"'()":                   "()",
+		"(= 'a (quote a))":      "true",
+	}
+	for src, expected := range tests {
+		res, err := in.Eval(src)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if res.String() != expected {
+			t.Errorf("%s: expected %s, got %s", src, expected, res)
+			continue
+		}
+		again, err := in.Eval("(do '" + res.String() + ")")
+		if err != nil {
+			t.Errorf("%s: reading back %s: %v", src, res, err)
+			continue
+		}
+		if !equal(res, again) {
+			t.Errorf("%s: %s reads back as %s", src, res, again)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package clojura
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SetLoadPath sets the directories load and require search for files that
+// are not found next to the file loading them.
+func (in *Interpreter) SetLoadPath(dirs []string) {
+	in.loadPath = dirs
+}
+
+// resolveFile returns the absolute path of the file name refers to. A
+// relative name is looked up next to the file being loaded, then in the
+// directories of the load path and then in the working directory.
+func (in *Interpreter) resolveFile(name string) (string, error) {
+	var dirs []string
+	if !filepath.IsAbs(name) {
+		if n := len(in.loading); n > 0 && filepath.IsAbs(in.loading[n-1]) {
+			dirs = append(dirs, filepath.Dir(in.loading[n-1]))
+		}
+		dirs = append(dirs, in.loadPath...)
+	}
+	for _, dir := range append(dirs, "") {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return filepath.Abs(path)
+		}
+	}
+	return "", &os.PathError{Op: "load", Path: name, Err: os.ErrNotExist}
+}
+
+// loadModule loads the file at path unless it was loaded already. A relative
+// path names a file of the standard library. Loading a file that is still
+// being loaded, because it is required by a file it requires itself, raises
+// an exception.
+func (in *Interpreter) loadModule(path string) {
+	for i, loading := range in.loading {
+		if loading == path {
+			cycle := append(in.loading[i:len(in.loading):len(in.loading)], path)
+			throwf("Cyclic load dependency: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	if in.loaded[path] {
+		return
+	}
+	load := in.LoadFile
+	if !filepath.IsAbs(path) {
+		load = in.loadBundled
+	}
+	if err := load(path); err != nil {
+		if e, ok := err.(*Exception); ok {
+			panic(e)
+		}
+		throwf("Failed to load file '%s': %v", path, err)
+	}
+}
+
+func (in *Interpreter) coreLoad(args []Sexpr) Sexpr {
+	checkArity("load", args, 1)
+	var n string
+	switch name := args[0].(type) {
+	case String:
+		n = string(name)
+	case Literal:
+		n = string(name)
+	default:
+		throwf("load argument should be a string, got %s", args[0])
+	}
+
+	path, err := in.resolveFile(n)
+	if err != nil {
+		throwf("Could not locate %s on the load path", n)
+	}
+	in.loadModule(path)
+	return Nil
+}
@@ -0,0 +1,63 @@
+package clojura
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates the files, given by path relative to dir, with their
+// contents.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app/main.clj":      `(ns app.main (:require [util.text :as text])) (load "helper.clj") (load "helper.clj") (require 'util.text)`,
+		"app/helper.clj":    `(println "helper") (defn helper [] :helped)`,
+		"lib/util/text.clj": `(ns util.text) (println "text") (defn shout [s] (str s "!"))`,
+		"cycle/a.clj":       `(ns a (:require [b]))`,
+		"cycle/b.clj":       `(ns b (:require [a]))`,
+	})
+
+	var out bytes.Buffer
+	in := NewInterpreter(&out, &out)
+	in.SetLoadPath([]string{filepath.Join(dir, "lib")})
+	if err := in.LoadFile(filepath.Join(dir, "lib/util/text.clj")); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.LoadFile(filepath.Join(dir, "app/main.clj")); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "text\nhelper\n" {
+		t.Errorf("expected every module to be loaded once, got %q", out.String())
+	}
+	if in.Namespace() != "user" {
+		t.Errorf("expected to be back in user, got %s", in.Namespace())
+	}
+	res, err := in.Eval("(require '[util.text :as t]) [(t/shout \"hi\") (app.main/helper)]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.String() != `["hi!" :helped]` {
+		t.Errorf("unexpected result %s", res)
+	}
+
+	err = in.LoadFile(filepath.Join(dir, "cycle/a.clj"))
+	if err == nil || !strings.Contains(err.Error(), "Cyclic load dependency: ") ||
+		!strings.HasSuffix(err.Error(), filepath.Join(dir, "cycle/a.clj")) {
+		t.Errorf("expected a cyclic load error, got %v", err)
+	}
+}
@@ -0,0 +1,314 @@
+package clojura
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// coreNamespace is the namespace of the builtins and the core library.
+const coreNamespace = Literal("clojura.core")
+
+// Namespace is a named set of vars. Code is analyzed in the current
+// namespace of its interpreter: def interns vars there, and a symbol
+// resolves to a var interned in it, a var referred to from another
+// namespace or a public var of clojura.core, in that order. A qualified
+// symbol such as str/join names a var of a namespace or of an alias.
+type Namespace struct {
+	name    Literal
+	vars    map[Literal]*Var
+	refers  map[Literal]*Var
+	aliases map[Literal]*Namespace
+}
+
+func newNamespace(name Literal) *Namespace {
+	return &Namespace{
+		name:    name,
+		vars:    map[Literal]*Var{},
+		refers:  map[Literal]*Var{},
+		aliases: map[Literal]*Namespace{},
+	}
+}
+
+func (ns *Namespace) Type() CoreType {
+	return TypeNamespace
+}
+
+func (ns *Namespace) Bool() bool {
+	return true
+}
+
+func (ns *Namespace) String() string {
+	return string(ns.name)
+}
+
+func (ns *Namespace) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (ns *Namespace) Eval(c *Context) Sexpr {
+	return ns
+}
+
+// intern returns the var interned in the namespace under name, creating an
+// unbound one if needed.
+func (ns *Namespace) intern(name Literal) *Var {
+	v, ok := ns.vars[name]
+	if !ok {
+		v = &Var{ns: ns, name: name}
+		ns.vars[name] = v
+	}
+	return v
+}
+
+// splitSymbol splits a qualified symbol into its namespace and name.
+func splitSymbol(sym Literal) (Literal, Literal, bool) {
+	i := strings.IndexByte(string(sym), '/')
+	if i <= 0 || i == len(sym)-1 {
+		return "", sym, false
+	}
+	return sym[:i], sym[i+1:], true
+}
+
+// namespace returns the namespace with the given name, creating it if needed.
+func (in *Interpreter) namespace(name Literal) *Namespace {
+	ns, ok := in.namespaces[name]
+	if !ok {
+		ns = newNamespace(name)
+		in.namespaces[name] = ns
+	}
+	return ns
+}
+
+// inNs makes ns the current namespace.
+func (in *Interpreter) inNs(ns *Namespace) {
+	in.ns = ns
+	in.core.intern("*ns*").value = ns
+}
+
+// Namespace returns the name of the current namespace.
+func (in *Interpreter) Namespace() string {
+	return string(in.ns.name)
+}
+
+// lookupVar returns the var sym refers to in the current namespace, or nil
+// if it refers to none.
+func (in *Interpreter) lookupVar(sym Literal) (*Var, error) {
+	if nsName, name, ok := splitSymbol(sym); ok {
+		ns, ok := in.ns.aliases[nsName]
+		if !ok {
+			ns, ok = in.namespaces[nsName]
+		}
+		if !ok {
+			return nil, fmt.Errorf("No such namespace: %s", nsName)
+		}
+		v, ok := ns.vars[name]
+		if !ok {
+			return nil, fmt.Errorf("No such var: %s", sym)
+		}
+		if v.private && ns != in.ns {
+			return nil, fmt.Errorf("var: %s is not public", v)
+		}
+		return v, nil
+	}
+	if v, ok := in.ns.vars[sym]; ok {
+		return v, nil
+	}
+	if v, ok := in.ns.refers[sym]; ok {
+		return v, nil
+	}
+	if v, ok := in.core.vars[sym]; ok && !v.private {
+		return v, nil
+	}
+	return nil, nil
+}
+
+// mappings returns every var a bare symbol can refer to in ns.
+func (in *Interpreter) mappings(ns *Namespace) map[Literal]*Var {
+	res := map[Literal]*Var{}
+	for name, v := range in.core.vars {
+		if !v.private {
+			res[name] = v
+		}
+	}
+	for name, v := range ns.refers {
+		res[name] = v
+	}
+	for name, v := range ns.vars {
+		res[name] = v
+	}
+	return res
+}
+
+// nsNode implements (ns name (:require spec*)*), which makes name the
+// current namespace, creating it if needed, and requires the given specs
+// in it.
+type nsNode struct {
+	node
+	name     Literal
+	requires []Sexpr
+}
+
+func (a *analyzer) analyzeNs(e *Expression) Sexpr {
+	if len(e.Elements) < 2 {
+		a.errorf("ns requires a name")
+	}
+	name, ok := e.Elements[1].(Literal)
+	if !ok {
+		a.errorf("ns name should be a literal, got %s", e.Elements[1])
+	}
+	res := &nsNode{node: node{e}, name: name}
+	for _, clause := range e.Elements[2:] {
+		if _, ok := clause.(String); ok {
+			continue
+		}
+		c, ok := clause.(*Expression)
+		if !ok || len(c.Elements) == 0 || c.Elements[0] != Intern("require") {
+			a.errorf("Unsupported ns clause: %s", clause)
+		}
+		res.requires = append(res.requires, mapForms(c.Elements[1:], formToData)...)
+	}
+	return res
+}
+
+func (n *nsNode) Eval(c *Context) Sexpr {
+	in := c.interp
+	in.inNs(in.namespace(n.name))
+	for _, spec := range n.requires {
+		in.require(spec)
+	}
+	return Nil
+}
+
+// require makes a namespace available in the current one. spec is either
+// the name of the namespace or a vector holding the name followed by
+// options: :as alias, and :refer with a vector of names or :all.
+func (in *Interpreter) require(spec Sexpr) {
+	var opts []Sexpr
+	if v, ok := spec.(*Vector); ok && v.Length() > 0 {
+		opts = v.Slice()
+		spec, opts = opts[0], opts[1:]
+	}
+	name, ok := spec.(Literal)
+	if !ok {
+		throwf("require spec should be a name or a vector, got %s", spec)
+	}
+	if len(opts)%2 != 0 {
+		throwf("require options should be pairs, got %s", NewVector(opts...))
+	}
+	ns := in.loadNamespace(name)
+	for i := 0; i < len(opts); i += 2 {
+		switch opts[i] {
+		case Intern("as"):
+			alias, ok := opts[i+1].(Literal)
+			if !ok {
+				throwf(":as should be followed by a name, got %s", opts[i+1])
+			}
+			in.ns.aliases[alias] = ns
+		case Intern("refer"):
+			in.refer(ns, opts[i+1])
+		default:
+			throwf("Unsupported require option: %s", opts[i])
+		}
+	}
+}
+
+// refer maps the public vars of ns named by names, or all of them when names
+// is :all, in the current namespace.
+func (in *Interpreter) refer(ns *Namespace, names Sexpr) {
+	if names == Intern("all") {
+		for name, v := range ns.vars {
+			if !v.private {
+				in.ns.refers[name] = v
+			}
+		}
+		return
+	}
+	for _, name := range vectorArg(":refer", names).Slice() {
+		n, ok := name.(Literal)
+		if !ok {
+			throwf(":refer should hold names, got %s", name)
+		}
+		v, ok := ns.vars[n]
+		if !ok {
+			throwf("%s does not exist in %s", n, ns.name)
+		}
+		if v.private {
+			throwf("%s is not public", v)
+		}
+		in.ns.refers[n] = v
+	}
+}
+
+// loadNamespace returns the namespace with the given name, loading the file
+// named after it unless it was loaded already: foo.bar-baz is read from
+// foo/bar_baz.clj, which is taken from the standard library if it is part
+// of it. A namespace without a file, such as one created at the REPL, is
+// returned as it is.
+func (in *Interpreter) loadNamespace(name Literal) *Namespace {
+	file := strings.NewReplacer(".", "/", "-", "_").Replace(string(name)) + ".clj"
+	if isBundled(file) {
+		in.loadModule(file)
+	} else if path, err := in.resolveFile(file); err == nil {
+		in.loadModule(path)
+	} else if _, ok := in.namespaces[name]; !ok {
+		throwf("Could not locate %s to load namespace %s", file, name)
+	}
+	ns, ok := in.namespaces[name]
+	if !ok {
+		throwf("Namespace %s not found after loading %s", name, file)
+	}
+	return ns
+}
+
+// namespaceArg returns the namespace named by s.
+func (in *Interpreter) namespaceArg(name string, s Sexpr) *Namespace {
+	switch t := s.(type) {
+	case *Namespace:
+		return t
+	case Literal:
+		if ns, ok := in.namespaces[t]; ok {
+			return ns
+		}
+		throwf("No namespace: %s found", t)
+	}
+	throwf("%s argument should be a namespace or a symbol, got %s", name, s)
+	return nil
+}
+
+func (in *Interpreter) coreRequire(args []Sexpr) Sexpr {
+	for _, spec := range args {
+		in.require(spec)
+	}
+	return Nil
+}
+
+func (in *Interpreter) coreInNs(args []Sexpr) Sexpr {
+	checkArity("in-ns", args, 1)
+	name, ok := args[0].(Literal)
+	if !ok {
+		throwf("in-ns argument should be a symbol, got %s", args[0])
+	}
+	in.inNs(in.namespace(name))
+	return in.ns
+}
+
+func (in *Interpreter) coreNsPublics(args []Sexpr) Sexpr {
+	checkArity("ns-publics", args, 1)
+	res := emptyMap
+	for name, v := range in.namespaceArg("ns-publics", args[0]).vars {
+		if !v.private {
+			res = res.Assoc(name, v)
+		}
+	}
+	return res
+}
+
+func (in *Interpreter) coreNsMap(args []Sexpr) Sexpr {
+	checkArity("ns-map", args, 1)
+	res := emptyMap
+	for name, v := range in.mappings(in.namespaceArg("ns-map", args[0])) {
+		res = res.Assoc(name, v)
+	}
+	return res
+}
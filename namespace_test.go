@@ -0,0 +1,59 @@
+package clojura
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNamespaces(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	_, err := in.Eval(`
+(ns geometry.shapes)
+(defn- square [x] (* x x))
+(defn area "Area of a square." [side] (square side))
+(defmacro twice [x] ` + "`" + `(area (+ ~x ~x)))
+(ns user (:require [geometry.shapes :as shapes :refer [twice]]))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.Namespace() != "user" {
+		t.Errorf("expected to be back in user, got %s", in.Namespace())
+	}
+	// The tests run in order, as some of them change the current namespace.
+	tests := []struct {
+		src, expected string
+	}{
+		{"(shapes/area 3)", "9"},
+		{"(geometry.shapes/area 2)", "4"},
+		{"(twice 1)", "4"},
+		{"(get (ns-publics 'geometry.shapes) 'area)", "#'geometry.shapes/area"},
+		{"(contains? (ns-publics 'geometry.shapes) 'square)", "false"},
+		{"(get (ns-map 'user) 'twice)", "#'geometry.shapes/twice"},
+		{"(get (ns-map 'user) 'map)", "#'clojura.core/map"},
+		{"(do (def map 1) map)", "1"},
+		{"(in-ns 'other) (def x 2) *ns*", "other"},
+		{"(in-ns 'user) other/x", "2"},
+		{"(do (ns foo.bar) (def y 1) foo.bar/y)", "1"},
+		{`(do (ns user) (require '[clojura.string :as s]) (s/join "," [1 2]))`, `"1,2"`},
+	}
+	for _, test := range tests {
+		res, err := in.Eval(test.src)
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+		if res.String() != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.src, test.expected, res)
+		}
+	}
+
+	errors := map[string]string{
+		"(shapes/square 2)":   "var: #'geometry.shapes/square is not public",
+		"(area 2)":            "Unable to resolve symbol: area",
+		"(nope/area 2)":       "No such namespace: nope",
+		"(shapes/nope 2)":     "No such var: shapes/nope",
+		"(_filter odd? '(1))": "Unable to resolve symbol: _filter",
+		"(require 'missing)":  "Could not locate missing.clj",
+	}
+	checkEvalErrors(t, in, errors)
+}
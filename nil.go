@@ -0,0 +1,68 @@
+package clojura
+
+import (
+	"errors"
+)
+
+type nilValue struct{}
+
+// Nil is the only value of the nil type. It is falsey, prints as nil and is
+// what every form without a meaningful result evaluates to.
+var Nil Sexpr = nilValue{}
+
+func (n nilValue) Bool() bool {
+	return false
+}
+
+func (n nilValue) String() string {
+	return "nil"
+}
+
+func (n nilValue) Type() CoreType {
+	return TypeNil
+}
+
+func (n nilValue) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (n nilValue) Eval(c *Context) Sexpr {
+	return n
+}
+
+// orNil turns a missing Go value into Nil.
+func orNil(s Sexpr) Sexpr {
+	if s == nil {
+		return Nil
+	}
+	return s
+}
+
+func coreIsNil(args []Sexpr) Sexpr {
+	checkArity("nil?", args, 1)
+	return Boolean(args[0] == Nil)
+}
+
+func coreIsSome(args []Sexpr) Sexpr {
+	checkArity("some?", args, 1)
+	return Boolean(args[0] != Nil)
+}
+
+// coreFnil wraps f so that nil arguments are replaced by the given defaults,
+// one default per leading argument.
+func coreFnil(args []Sexpr) Sexpr {
+	if len(args) < 2 {
+		throwf("Wrong number of args (%d) passed to fnil", len(args))
+	}
+	f, defaults := args[0], args[1:]
+	return coreF(func(args []Sexpr) Sexpr {
+		patched := make([]Sexpr, len(args))
+		copy(patched, args)
+		for i := 0; i < len(defaults) && i < len(patched); i++ {
+			if patched[i] == Nil {
+				patched[i] = defaults[i]
+			}
+		}
+		return call(f, patched)
+	})
+}
@@ -0,0 +1,20 @@
+package clojura
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNil(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	checkEval(t, in, map[string]string{
+		"nil":                                 "nil",
+		"[(nil? nil) (nil? false) (some? 0)]": "[true false true]",
+		"[(if nil 1 2) (if false 1 2) (if 0 1 2) (if \"\" 1 2) (if 'a 1 2)]": "[2 2 1 1 1]",
+		"(= nil false)": "false",
+		"[(first nil) (next nil) (get nil :a) (:a nil)]": "[nil nil nil nil]",
+		"(str nil \"a\" nil)":                            `"a"`,
+		"((fnil + 0) nil 2)":                             "2",
+		"(seq [])":                                       "nil",
+	})
+}
@@ -0,0 +1,574 @@
+package clojura
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Float is a double precision floating point number.
+type Float float64
+
+func (f Float) Bool() bool {
+	return true
+}
+
+func (f Float) String() string {
+	v := float64(f)
+	switch {
+	case math.IsNaN(v):
+		return "##NaN"
+	case math.IsInf(v, 1):
+		return "##Inf"
+	case math.IsInf(v, -1):
+		return "##-Inf"
+	}
+	if abs := math.Abs(v); abs == 0 || (abs >= 1e-3 && abs < 1e7) {
+		s := strconv.FormatFloat(v, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s
+	}
+	s := strconv.FormatFloat(v, 'E', -1, 64)
+	mantissa, exp, _ := strings.Cut(s, "E")
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	n, _ := strconv.Atoi(exp)
+	return mantissa + "E" + strconv.Itoa(n)
+}
+
+func (f Float) Type() CoreType {
+	return TypeFloat
+}
+
+func (f Float) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (f Float) Eval(c *Context) Sexpr {
+	return f
+}
+
+// BigInt is an arbitrary precision integer. The wrapped value is never
+// modified after construction.
+type BigInt struct {
+	val *big.Int
+}
+
+// NewBigInt creates a BigInt holding a copy of v.
+func NewBigInt(v *big.Int) BigInt {
+	return BigInt{new(big.Int).Set(v)}
+}
+
+func (b BigInt) Int() *big.Int {
+	return new(big.Int).Set(b.val)
+}
+
+func (b BigInt) Bool() bool {
+	return true
+}
+
+func (b BigInt) String() string {
+	return b.val.String() + "N"
+}
+
+func (b BigInt) Type() CoreType {
+	return TypeBigInt
+}
+
+func (b BigInt) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (b BigInt) Eval(c *Context) Sexpr {
+	return b
+}
+
+// Ratio is an exact fraction whose denominator is never 1.
+type Ratio struct {
+	val *big.Rat
+}
+
+// NewRatio creates the exact number equal to v: a Ratio, or a BigInt when v
+// is a whole number.
+func NewRatio(v *big.Rat) Sexpr {
+	if v.IsInt() {
+		return BigInt{new(big.Int).Set(v.Num())}
+	}
+	return Ratio{new(big.Rat).Set(v)}
+}
+
+func (r Ratio) Rat() *big.Rat {
+	return new(big.Rat).Set(r.val)
+}
+
+func (r Ratio) Bool() bool {
+	return true
+}
+
+func (r Ratio) String() string {
+	return r.val.String()
+}
+
+func (r Ratio) Type() CoreType {
+	return TypeRatio
+}
+
+func (r Ratio) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (r Ratio) Eval(c *Context) Sexpr {
+	return r
+}
+
+var (
+	intPattern   = regexp.MustCompile(`^[-+]?[0-9]+N?$`)
+	ratioPattern = regexp.MustCompile(`^[-+]?[0-9]+/[0-9]+$`)
+	floatPattern = regexp.MustCompile(`^[-+]?[0-9]+(\.[0-9]*)?([eE][-+]?[0-9]+)?$`)
+)
+
+// isNumber reports whether the token looks like a number literal, which is
+// the case for everything starting with a digit or a sign and a digit.
+func isNumber(t string) bool {
+	if len(t) > 1 && (t[0] == '-' || t[0] == '+') {
+		t = t[1:]
+	}
+	return len(t) > 0 && t[0] >= '0' && t[0] <= '9'
+}
+
+// parseNumber reads a number literal: 42, 42N, 22/7, 1.5 or 1e3. Integers that
+// do not fit into a Number become BigInts.
+func parseNumber(t string) (Sexpr, error) {
+	switch {
+	case intPattern.MatchString(t):
+		if !strings.HasSuffix(t, "N") {
+			n, err := strconv.ParseInt(t, 10, strconv.IntSize)
+			if err == nil {
+				return Number(n), nil
+			}
+		}
+		v, _ := new(big.Int).SetString(strings.TrimSuffix(t, "N"), 10)
+		return BigInt{v}, nil
+	case ratioPattern.MatchString(t):
+		v, ok := new(big.Rat).SetString(t)
+		if !ok {
+			return nil, fmt.Errorf("Divide by zero: %s", t)
+		}
+		if v.IsInt() && v.Num().IsInt64() {
+			return Number(v.Num().Int64()), nil
+		}
+		return NewRatio(v), nil
+	case floatPattern.MatchString(t):
+		v, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid number: %s", t)
+		}
+		return Float(v), nil
+	}
+	return nil, fmt.Errorf("Invalid number: %s", t)
+}
+
+// Numbers are combined in the widest category of their operands:
+// Number < BigInt < Ratio < Float.
+type numCategory uint8
+
+const (
+	intCategory numCategory = iota
+	bigCategory
+	ratioCategory
+	floatCategory
+)
+
+func category(name string, s Sexpr) numCategory {
+	switch s.(type) {
+	case Number:
+		return intCategory
+	case BigInt:
+		return bigCategory
+	case Ratio:
+		return ratioCategory
+	case Float:
+		return floatCategory
+	}
+	throwf("%s arguments should be numbers, got %s", name, s)
+	return 0
+}
+
+func isNumeric(s Sexpr) bool {
+	switch s.(type) {
+	case Number, BigInt, Ratio, Float:
+		return true
+	}
+	return false
+}
+
+func toBig(s Sexpr) *big.Int {
+	switch n := s.(type) {
+	case Number:
+		return big.NewInt(int64(n))
+	case BigInt:
+		return n.val
+	}
+	panic("not an integer")
+}
+
+func toRat(s Sexpr) *big.Rat {
+	switch n := s.(type) {
+	case Number, BigInt:
+		return new(big.Rat).SetInt(toBig(n))
+	case Ratio:
+		return n.val
+	}
+	panic("not a rational")
+}
+
+func toFloat(s Sexpr) float64 {
+	switch n := s.(type) {
+	case Number:
+		return float64(n)
+	case BigInt:
+		f, _ := new(big.Float).SetInt(n.val).Float64()
+		return f
+	case Ratio:
+		f, _ := n.val.Float64()
+		return f
+	case Float:
+		return float64(n)
+	}
+	panic("not a number")
+}
+
+// numOp is a binary arithmetic operation defined for every category. The int
+// variant reports false when its result does not fit into a Number.
+type numOp struct {
+	name    string
+	promote bool
+	int     func(a, b Number) (Sexpr, bool)
+	big     func(a, b *big.Int) Sexpr
+	ratio   func(a, b *big.Rat) Sexpr
+	float   func(a, b float64) Sexpr
+}
+
+func (op *numOp) apply(a, b Sexpr) Sexpr {
+	ca, cb := category(op.name, a), category(op.name, b)
+	switch max(ca, cb) {
+	case intCategory:
+		if res, ok := op.int(a.(Number), b.(Number)); ok {
+			return res
+		}
+		if !op.promote {
+			throwf("integer overflow")
+		}
+		return op.big(toBig(a), toBig(b))
+	case bigCategory:
+		return op.big(toBig(a), toBig(b))
+	case ratioCategory:
+		return op.ratio(toRat(a), toRat(b))
+	}
+	return op.float(toFloat(a), toFloat(b))
+}
+
+// fold applies op to args from left to right, starting with identity.
+func (op *numOp) fold(identity Sexpr, args []Sexpr) Sexpr {
+	if len(args) == 0 {
+		return identity
+	}
+	res := args[0]
+	if len(args) == 1 {
+		category(op.name, res)
+		return res
+	}
+	for _, arg := range args[1:] {
+		res = op.apply(res, arg)
+	}
+	return res
+}
+
+func newAddOp(name string, promote bool) *numOp {
+	return &numOp{
+		name:    name,
+		promote: promote,
+		int: func(a, b Number) (Sexpr, bool) {
+			r := a + b
+			return r, (r^a)&(r^b) >= 0
+		},
+		big: func(a, b *big.Int) Sexpr {
+			return BigInt{new(big.Int).Add(a, b)}
+		},
+		ratio: func(a, b *big.Rat) Sexpr {
+			return NewRatio(new(big.Rat).Add(a, b))
+		},
+		float: func(a, b float64) Sexpr {
+			return Float(a + b)
+		},
+	}
+}
+
+func newSubOp(name string, promote bool) *numOp {
+	return &numOp{
+		name:    name,
+		promote: promote,
+		int: func(a, b Number) (Sexpr, bool) {
+			r := a - b
+			return r, (a^b)&(a^r) >= 0
+		},
+		big: func(a, b *big.Int) Sexpr {
+			return BigInt{new(big.Int).Sub(a, b)}
+		},
+		ratio: func(a, b *big.Rat) Sexpr {
+			return NewRatio(new(big.Rat).Sub(a, b))
+		},
+		float: func(a, b float64) Sexpr {
+			return Float(a - b)
+		},
+	}
+}
+
+func newMulOp(name string, promote bool) *numOp {
+	return &numOp{
+		name:    name,
+		promote: promote,
+		int: func(a, b Number) (Sexpr, bool) {
+			if a == 0 || b == 0 {
+				return Number(0), true
+			}
+			r := a * b
+			return r, r/b == a && !(a == -1 && b == math.MinInt) && !(b == -1 && a == math.MinInt)
+		},
+		big: func(a, b *big.Int) Sexpr {
+			return BigInt{new(big.Int).Mul(a, b)}
+		},
+		ratio: func(a, b *big.Rat) Sexpr {
+			return NewRatio(new(big.Rat).Mul(a, b))
+		},
+		float: func(a, b float64) Sexpr {
+			return Float(a * b)
+		},
+	}
+}
+
+var (
+	addOp        = newAddOp("+", false)
+	addPromoteOp = newAddOp("+'", true)
+	subOp        = newSubOp("-", false)
+	subPromoteOp = newSubOp("-'", true)
+	mulOp        = newMulOp("*", false)
+	mulPromoteOp = newMulOp("*'", true)
+	divOp        = &numOp{
+		name:    "/",
+		promote: true,
+		int: func(a, b Number) (Sexpr, bool) {
+			if b == 0 {
+				throwf("Divide by zero")
+			}
+			if a%b != 0 {
+				return NewRatio(big.NewRat(int64(a), int64(b))), true
+			}
+			return a / b, !(a == math.MinInt && b == -1)
+		},
+		big: func(a, b *big.Int) Sexpr {
+			if b.Sign() == 0 {
+				throwf("Divide by zero")
+			}
+			return NewRatio(new(big.Rat).SetFrac(a, b))
+		},
+		ratio: func(a, b *big.Rat) Sexpr {
+			if b.Sign() == 0 {
+				throwf("Divide by zero")
+			}
+			return NewRatio(new(big.Rat).Quo(a, b))
+		},
+		float: func(a, b float64) Sexpr {
+			return Float(a / b)
+		},
+	}
+)
+
+func coreAdd(args []Sexpr) Sexpr {
+	return addOp.fold(Number(0), args)
+}
+
+func coreAddPromote(args []Sexpr) Sexpr {
+	return addPromoteOp.fold(Number(0), args)
+}
+
+func coreSub(args []Sexpr) Sexpr {
+	return subtract(subOp, args)
+}
+
+func coreSubPromote(args []Sexpr) Sexpr {
+	return subtract(subPromoteOp, args)
+}
+
+// subtract negates a single argument and subtracts the rest from the first
+// one otherwise.
+func subtract(op *numOp, args []Sexpr) Sexpr {
+	if len(args) < 1 {
+		throwf("Wrong number of args (0) passed to %s", op.name)
+	}
+	if len(args) == 1 {
+		return op.apply(Number(0), args[0])
+	}
+	return op.fold(nil, args)
+}
+
+func coreMul(args []Sexpr) Sexpr {
+	return mulOp.fold(Number(1), args)
+}
+
+func coreMulPromote(args []Sexpr) Sexpr {
+	return mulPromoteOp.fold(Number(1), args)
+}
+
+func coreDiv(args []Sexpr) Sexpr {
+	if len(args) < 1 {
+		throwf("Wrong number of args (0) passed to /")
+	}
+	if len(args) == 1 {
+		return divOp.apply(Number(1), args[0])
+	}
+	return divOp.fold(nil, args)
+}
+
+// compareNum compares two numbers of any category. It reports false if they
+// can't be ordered, which is the case for NaN.
+func compareNum(name string, a, b Sexpr) (int, bool) {
+	ca, cb := category(name, a), category(name, b)
+	switch max(ca, cb) {
+	case intCategory:
+		x, y := a.(Number), b.(Number)
+		if x < y {
+			return -1, true
+		} else if x > y {
+			return 1, true
+		}
+		return 0, true
+	case bigCategory:
+		return toBig(a).Cmp(toBig(b)), true
+	case ratioCategory:
+		return toRat(a).Cmp(toRat(b)), true
+	}
+	x, y := toFloat(a), toFloat(b)
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return 0, false
+	}
+	if x < y {
+		return -1, true
+	} else if x > y {
+		return 1, true
+	}
+	return 0, true
+}
+
+// numEqual is the equality used by =: integers, ratios and floats never
+// equal each other, even when they hold the same value.
+func numEqual(a, b Sexpr) bool {
+	ca, cb := category("=", a), category("=", b)
+	if ca == bigCategory {
+		ca = intCategory
+	}
+	if cb == bigCategory {
+		cb = intCategory
+	}
+	if ca != cb {
+		return false
+	}
+	res, ok := compareNum("=", a, b)
+	return ok && res == 0
+}
+
+func coreGreat(args []Sexpr) Sexpr {
+	return compareNumbers(">", args, func(res int) bool { return res > 0 })
+}
+
+func coreLess(args []Sexpr) Sexpr {
+	return compareNumbers("<", args, func(res int) bool { return res < 0 })
+}
+
+func coreLessEq(args []Sexpr) Sexpr {
+	return compareNumbers("<=", args, func(res int) bool { return res <= 0 })
+}
+
+func coreGreatEq(args []Sexpr) Sexpr {
+	return compareNumbers(">=", args, func(res int) bool { return res >= 0 })
+}
+
+func coreNumEq(args []Sexpr) Sexpr {
+	return compareNumbers("==", args, func(res int) bool { return res == 0 })
+}
+
+// compareNumbers checks that cmp holds for the comparison of every
+// consecutive pair of args.
+func compareNumbers(name string, args []Sexpr, cmp func(int) bool) Sexpr {
+	if len(args) < 1 {
+		throwf("Wrong number of args (0) passed to %s", name)
+	}
+
+	res := True
+	category(name, args[0])
+	for i := 1; i < len(args); i++ {
+		r, ok := compareNum(name, args[i-1], args[i])
+		if !ok || !cmp(r) {
+			res = False
+		}
+	}
+	return res
+}
+
+func coreIsNumber(args []Sexpr) Sexpr {
+	checkArity("number?", args, 1)
+	return Boolean(isNumeric(args[0]))
+}
+
+func coreIsInteger(args []Sexpr) Sexpr {
+	checkArity("integer?", args, 1)
+	switch args[0].(type) {
+	case Number, BigInt:
+		return True
+	}
+	return False
+}
+
+func coreIsFloat(args []Sexpr) Sexpr {
+	checkArity("float?", args, 1)
+	_, ok := args[0].(Float)
+	return Boolean(ok)
+}
+
+func coreIsRatio(args []Sexpr) Sexpr {
+	checkArity("ratio?", args, 1)
+	_, ok := args[0].(Ratio)
+	return Boolean(ok)
+}
+
+func coreDouble(args []Sexpr) Sexpr {
+	checkArity("double", args, 1)
+	category("double", args[0])
+	return Float(toFloat(args[0]))
+}
+
+func coreBigint(args []Sexpr) Sexpr {
+	checkArity("bigint", args, 1)
+	switch n := args[0].(type) {
+	case Number, BigInt:
+		return BigInt{toBig(n)}
+	case Ratio:
+		return BigInt{new(big.Int).Quo(n.val.Num(), n.val.Denom())}
+	case Float:
+		f := float64(n)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			throwf("bigint argument should be finite, got %s", n)
+		}
+		v, _ := big.NewFloat(f).Int(nil)
+		return BigInt{v}
+	}
+	throwf("bigint argument should be a number, got %s", args[0])
+	return nil
+}
@@ -0,0 +1,30 @@
+package clojura
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNumericTower(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	tests := map[string]string{
+		"(+ 1 2)":                    "3",
+		"(+ 1 1.5)":                  "2.5",
+		"(+ 1/2 1/3)":                "5/6",
+		"(* 2/3 3/2)":                "1N",
+		"(/ 6 3)":                    "2",
+		"(/ 1 3)":                    "1/3",
+		"(- 5)":                      "-5",
+		"(+ 1N 1)":                   "2N",
+		"(+' 9223372036854775807 1)": "9223372036854775808N",
+		"(*' 9223372036854775807 2)": "18446744073709551614N",
+		"(do 100000000000000000000)": "100000000000000000000N",
+		"(do 1e3)":                   "1000.0",
+		"(< 1 3/2 2.0 3N)":           "true",
+		"(= 1 1.0)":                  "false",
+		"(== 1 1.0)":                 "true",
+		"(= 1 1N)":                   "true",
+		"(try (+ 9223372036854775807 1) (catch Exception e (ex-message e)))": `"integer overflow"`,
+	}
+	checkEval(t, in, tests)
+}
@@ -1,9 +1,11 @@
-package main
+package clojura
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"strconv"
+	"strings"
 )
 
 type CoreType uint8
@@ -17,6 +19,19 @@ const (
 	TypeMacros
 	TypeList
 	TypeRecur
+	TypeException
+	TypeString
+	TypeFloat
+	TypeBigInt
+	TypeRatio
+	TypeVector
+	TypeMap
+	TypeKeyword
+	TypeNil
+	TypeTailCall
+	TypeVar
+	TypeNamespace
+	TypeSet
 )
 
 type Sexpr interface {
@@ -61,6 +76,7 @@ func (r *Recur) Eval(c *Context) Sexpr {
 
 type Expression struct {
 	Elements []Sexpr
+	Pos      Pos
 }
 
 func (e *Expression) Type() CoreType {
@@ -80,40 +96,71 @@ func (e *Expression) Append(s Sexpr) error {
 }
 
 func (e *Expression) String() string {
-	res := "("
-	for _, s := range e.Elements {
-		res += s.String() + " "
+	res := make([]string, len(e.Elements))
+	for i, s := range e.Elements {
+		if v, ok := s.(*Var); ok {
+			// An analyzed form shows the globals it refers to by name.
+			res[i] = string(v.name)
+			continue
+		}
+		res[i] = s.String()
 	}
-	res += ")"
-	return res
+	return "(" + strings.Join(res, " ") + ")"
 }
 
 func (e *Expression) Eval(c *Context) Sexpr {
+	return force(e.eval(c, false))
+}
+
+// eval evaluates the expression, leaving the forms in tail position of
+// special forms to the caller. When tail is set, calls are also left to the
+// caller rather than made.
+func (e *Expression) eval(c *Context, tail bool) Sexpr {
 	if len(e.Elements) < 1 {
-		return nil
+		return NewList()
 	}
 	f := e.Elements[0].Eval(c)
-	if f != nil && (f.Type() == TypeFunction || f.Type() == TypeMacros) {
-		if f.Type() == TypeMacros {
-			return f.(Macros).Create(c, e.Elements)
-		}
-		args := make([]Sexpr, len(e.Elements)-1)
-		for i, arg := range e.Elements[1:] {
-			args[i] = arg.Eval(c)
-		}
-		fun, ok := f.(Function)
-		if !ok {
-			return nil
-		}
-		return fun.Call(args)
+	if m, ok := f.(Macros); ok {
+		return m.Create(c, e.Elements)
 	}
-	return nil
+	fun, ok := f.(Function)
+	if !ok {
+		throwf("%s is not a function", e.Elements[0])
+	}
+	args := make([]Sexpr, len(e.Elements)-1)
+	for i, arg := range e.Elements[1:] {
+		args[i] = arg.Eval(c)
+	}
+	frame := Frame{Name: frameName(e.Elements[0], f), Pos: e.Pos}
+	if tail {
+		return &tailCall{fn: fun, args: args, frame: frame, interp: c.interp}
+	}
+	in := c.interp
+	in.pushFrame(frame)
+	res := fun.Call(args)
+	in.popFrame()
+	return res
+}
+
+// frameName returns the name the function f, called through the form head,
+// is shown with in stack traces.
+func frameName(head, f Sexpr) string {
+	if fn, ok := f.(*function); ok && fn.name != "" {
+		return fn.name
+	}
+	switch h := head.(type) {
+	case Literal, *local:
+		return head.String()
+	case *Var:
+		return string(h.name)
+	}
+	return "fn"
 }
 
 type Literal string
 
 func (l Literal) Bool() bool {
-	return false
+	return true
 }
 
 func (l Literal) String() string {
@@ -130,16 +177,17 @@ func (l Literal) Append(s Sexpr) error {
 
 func (l Literal) Eval(c *Context) Sexpr {
 	val, ok := c.Get(l)
-	if ok {
-		return val
+	if !ok {
+		throwf("Unable to resolve symbol: %s in this context", l)
 	}
-	return l
+	return val
 }
 
 type Number int
 
+// Bool returns true: like every value but nil and false, zero is truthy.
 func (n Number) Bool() bool {
-	return n != 0
+	return true
 }
 
 func (n Number) String() string {
@@ -185,6 +233,8 @@ func (b Boolean) Eval(c *Context) Sexpr {
 
 type Parser struct {
 	lexer *Lexer
+	// starts holds the position of each top-level form read by Parse.
+	starts []Pos
 }
 
 func NewParser(lexer *Lexer) *Parser {
@@ -193,14 +243,55 @@ func NewParser(lexer *Lexer) *Parser {
 	}
 }
 
-func (p *Parser) Parse() ([]Sexpr, error) {
-	resp := make([]Sexpr, 0)
-	match := 0
+// constants are the symbols the reader turns into values, so that they stay
+// the same when quoted.
+var constants = map[string]Sexpr{
+	"nil":   Nil,
+	"true":  True,
+	"false": False,
+}
+
+// openForm is a form the parser is still reading along with the delimiter
+// that closes it. Reader macros such as `x have no closer: they end with the
+// first complete form read after them.
+type openForm struct {
+	form   Sexpr
+	closer string
+}
 
-	var s Sexpr
-	stack := make([]Sexpr, 0)
+// readerMacros maps reader macro tokens to the special forms they wrap the
+// following form in.
+var readerMacros = map[string]Literal{
+	"'":  "quote",
+	"`":  "syntax-quote",
+	"~":  "unquote",
+	"~@": "unquote-splicing",
+}
 
-	eval := true
+func (p *Parser) Parse() ([]Sexpr, error) {
+	resp := make([]Sexpr, 0)
+	open := make([]openForm, 0)
+	p.starts = p.starts[:0]
+	var start Pos
+
+	// add puts a complete form into the innermost open form, completing
+	// any reader macros waiting for it.
+	add := func(s Sexpr) error {
+		for len(open) > 0 {
+			top := open[len(open)-1]
+			if err := top.form.Append(s); err != nil {
+				return err
+			}
+			if top.closer != "" {
+				return nil
+			}
+			open = open[:len(open)-1]
+			s = top.form
+		}
+		resp = append(resp, s)
+		p.starts = append(p.starts, start)
+		return nil
+	}
 
 	for {
 		t, err := p.lexer.ReadToken()
@@ -210,48 +301,82 @@ func (p *Parser) Parse() ([]Sexpr, error) {
 			}
 			return nil, err
 		}
-		switch t {
-		case "'":
-			log.Debug("EVAL FALSE")
-			eval = false
-		case "(":
-			match += 1
-			if s != nil {
-				stack = append(stack, s)
+		if len(open) == 0 {
+			start = t.Pos
+		}
+		if t.Kind == TokenString {
+			if err := add(String(t.Text)); err != nil {
+				return nil, err
 			}
-			if eval {
-				s = &Expression{}
-			} else {
-				s = NewList()
+			continue
+		}
+		switch t.Text {
+		case "'", "`", "~", "~@":
+			open = append(open, openForm{
+				form: &Expression{Elements: []Sexpr{readerMacros[t.Text]}, Pos: t.Pos},
+			})
+		case "(", "[", "{", "#{":
+			var f openForm
+			switch t.Text {
+			case "(":
+				f = openForm{form: &Expression{Pos: t.Pos}, closer: ")"}
+			case "[":
+				f = openForm{form: &vectorForm{Expression{Pos: t.Pos}}, closer: "]"}
+			case "{":
+				f = openForm{form: &mapForm{Expression{Pos: t.Pos}}, closer: "}"}
+			case "#{":
+				f = openForm{form: &setForm{Expression{Pos: t.Pos}}, closer: "}"}
 			}
-			eval = true
-		case ")":
-			if match-1 < 0 {
-				return nil, errors.New("unmatched pair")
+			open = append(open, f)
+		case ")", "]", "}":
+			if len(open) == 0 {
+				return nil, fmt.Errorf("%s: unmatched pair", t.Pos)
 			}
-			match -= 1
-			if len(stack) > 0 {
-				p := stack[len(stack)-1]
-				err := p.Append(s)
+			top := open[len(open)-1]
+			if top.closer != t.Text {
+				return nil, fmt.Errorf("%s: unmatched delimiter %s", t.Pos, t.Text)
+			}
+			open = open[:len(open)-1]
+			s := top.form
+			if f, ok := s.(*mapForm); ok {
+				m, err := f.build()
 				if err != nil {
-					return nil, err
+					return nil, fmt.Errorf("%s: %v", f.Pos, err)
 				}
-				s = p
-				stack = stack[:len(stack)-1]
-			} else {
-				resp = append(resp, s)
-				s = nil
+				s = m
+			} else if f, ok := s.(*vectorForm); ok {
+				s = NewVector(f.Elements...)
+			} else if f, ok := s.(*setForm); ok {
+				set, err := f.build()
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", f.Pos, err)
+				}
+				s = set
+			}
+			if err := add(s); err != nil {
+				return nil, err
 			}
 		default:
-			n, err := strconv.Atoi(t)
-			if err == nil {
-				s.Append(Number(n))
+			var s Sexpr
+			if isNumber(t.Text) {
+				n, err := parseNumber(t.Text)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", t.Pos, err)
+				}
+				s = n
+			} else if len(t.Text) > 1 && t.Text[0] == ':' {
+				s = Intern(t.Text[1:])
+			} else if c, ok := constants[t.Text]; ok {
+				s = c
 			} else {
-				s.Append(Literal(t))
+				s = Literal(t.Text)
+			}
+			if err := add(s); err != nil {
+				return nil, err
 			}
 		}
 	}
-	if match != 0 {
+	if len(open) != 0 {
 		return nil, errors.New("unmatched pair")
 	}
 	return resp, nil
@@ -0,0 +1,40 @@
+package clojura
+
+// recurMacro evaluates its arguments and hands them back to the enclosing
+// function or loop, which rebinds them and runs its body again. The
+// analyzer makes sure it is only used in tail position.
+func recurMacro(c *Context, args []Sexpr) Sexpr {
+	res := make([]Sexpr, len(args)-1)
+	for i, arg := range args[1:] {
+		res[i] = arg.Eval(c)
+	}
+	return &Recur{res}
+}
+
+// loopNode runs its body in a new frame holding its bindings, and again in
+// a fresh frame with the values passed to each recur.
+type loopNode struct {
+	node
+	bindings []binding
+	inits    []Sexpr
+	size     int
+	body     []Sexpr
+}
+
+func (l *loopNode) Eval(c *Context) Sexpr {
+	frame := newFrame(c, l.size)
+	for i, b := range l.bindings {
+		b.bind(frame, l.inits[i].Eval(frame))
+	}
+	for {
+		res := force(evalBody(frame, l.body))
+		r, ok := res.(*Recur)
+		if !ok {
+			return res
+		}
+		frame = newFrame(c, l.size)
+		for i, arg := range r.Args {
+			l.bindings[i].bind(frame, arg)
+		}
+	}
+}
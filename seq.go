@@ -0,0 +1,230 @@
+package clojura
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// Seqable is a collection whose elements can be walked as a sequence.
+type Seqable interface {
+	Sexpr
+	// Seq returns the elements of the collection, or nil when it is empty.
+	Seq() Seq
+}
+
+// Seq is a sequence: lists, lazy sequences and the sequences returned by
+// Seq. A sequence may be empty, in which case its own Seq method returns
+// nil.
+type Seq interface {
+	Seqable
+	// First returns the first element, or nil when the sequence is empty.
+	First() Sexpr
+	// Rest returns the elements after the first one, possibly none.
+	Rest() Seq
+}
+
+// Cons is a sequence made of an element followed by another sequence, which
+// may be lazy.
+type Cons struct {
+	first Sexpr
+	more  Seq
+}
+
+func (c *Cons) Seq() Seq {
+	return c
+}
+
+func (c *Cons) First() Sexpr {
+	return c.first
+}
+
+func (c *Cons) Rest() Seq {
+	return c.more
+}
+
+func (c *Cons) Type() CoreType {
+	return TypeList
+}
+
+func (c *Cons) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (c *Cons) String() string {
+	return formatSeq(c, readableString)
+}
+
+func (c *Cons) Eval(ctx *Context) Sexpr {
+	return c
+}
+
+func (c *Cons) Bool() bool {
+	return true
+}
+
+// sliceSeq is a sequence over the elements of a slice. It walks the
+// elements of maps, sets and strings.
+type sliceSeq struct {
+	items []Sexpr
+}
+
+// seqOfSlice returns a sequence over items, or nil when there are none.
+func seqOfSlice(items []Sexpr) Seq {
+	if len(items) == 0 {
+		return nil
+	}
+	return &sliceSeq{items}
+}
+
+func (s *sliceSeq) Seq() Seq {
+	return seqOfSlice(s.items)
+}
+
+func (s *sliceSeq) First() Sexpr {
+	if len(s.items) == 0 {
+		return Nil
+	}
+	return s.items[0]
+}
+
+func (s *sliceSeq) Rest() Seq {
+	if len(s.items) == 0 {
+		return s
+	}
+	return &sliceSeq{s.items[1:]}
+}
+
+func (s *sliceSeq) Type() CoreType {
+	return TypeList
+}
+
+func (s *sliceSeq) Append(e Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (s *sliceSeq) String() string {
+	return formatSeq(s, readableString)
+}
+
+func (s *sliceSeq) Eval(c *Context) Sexpr {
+	return s
+}
+
+func (s *sliceSeq) Bool() bool {
+	return true
+}
+
+// seqOf returns the elements of s, a collection or nil, as a sequence, or
+// nil when there are none.
+func seqOf(s Sexpr) Seq {
+	switch t := s.(type) {
+	case nilValue:
+		return nil
+	case Seqable:
+		return t.Seq()
+	}
+	throwf("Don't know how to create a seq from %s", s)
+	return nil
+}
+
+// first returns the first element of a collection.
+func first(s Sexpr) Sexpr {
+	if seq := seqOf(s); seq != nil {
+		return seq.First()
+	}
+	return Nil
+}
+
+// rest returns the elements of a collection after the first one.
+func rest(s Sexpr) Seq {
+	if seq := seqOf(s); seq != nil {
+		return seq.Rest()
+	}
+	return NewList()
+}
+
+// seqItems realizes a collection and returns its elements.
+func seqItems(s Sexpr) []Sexpr {
+	var res []Sexpr
+	for seq := seqOf(s); seq != nil; seq = seq.Rest().Seq() {
+		res = append(res, seq.First())
+	}
+	return res
+}
+
+// count returns the number of elements of a collection, walking it unless
+// its length is known.
+func count(s Sexpr) int {
+	switch t := s.(type) {
+	case interface{ Length() int }:
+		return t.Length()
+	case String:
+		return utf8.RuneCountInString(string(t))
+	}
+	n := 0
+	for seq := seqOf(s); seq != nil; seq = seq.Rest().Seq() {
+		n++
+	}
+	return n
+}
+
+// formatSeq prints a sequence as a list, showing every element with show.
+func formatSeq(s Seq, show func(Sexpr) string) string {
+	items := seqItems(s)
+	res := make([]string, len(items))
+	for i, item := range items {
+		res[i] = show(item)
+	}
+	return "(" + strings.Join(res, " ") + ")"
+}
+
+// orNilSeq returns s, or nil when it is empty.
+func orNilSeq(s Seq) Sexpr {
+	if s == nil {
+		return Nil
+	}
+	return s
+}
+
+func coreSeq(args []Sexpr) Sexpr {
+	checkArity("seq", args, 1)
+	return orNilSeq(seqOf(args[0]))
+}
+
+func coreFirst(args []Sexpr) Sexpr {
+	checkArity("first", args, 1)
+	return first(args[0])
+}
+
+func coreRest(args []Sexpr) Sexpr {
+	checkArity("rest", args, 1)
+	return rest(args[0])
+}
+
+func coreNext(args []Sexpr) Sexpr {
+	checkArity("next", args, 1)
+	return orNilSeq(rest(args[0]).Seq())
+}
+
+func coreCount(args []Sexpr) Sexpr {
+	checkArity("count", args, 1)
+	return Number(count(args[0]))
+}
+
+// coreCons adds an element in front of a list, or of any other collection
+// without realizing it.
+func coreCons(args []Sexpr) Sexpr {
+	checkArity("cons", args, 2)
+	switch t := args[1].(type) {
+	case *List:
+		return t.Add(args[0])
+	case Seq:
+		return &Cons{args[0], t}
+	}
+	s := seqOf(args[1])
+	if s == nil {
+		return NewList().Add(args[0])
+	}
+	return &Cons{args[0], s}
+}
@@ -0,0 +1,54 @@
+package clojura
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSeqs(t *testing.T) {
+	tests := map[string]string{
+		"(first '(1 2))":                   "1",
+		"(first [1 2])":                    "1",
+		"(first \"ab\")":                   `"a"`,
+		"(first {:a 1})":                   "[:a 1]",
+		"(first #{:a})":                    ":a",
+		"(first nil)":                      "nil",
+		"(first (range 3))":                "0",
+		"(rest [1 2 3])":                   "(2 3)",
+		"(rest [])":                        "()",
+		"(rest nil)":                       "()",
+		"(next [1])":                       "nil",
+		"(next \"abc\")":                   `("b" "c")`,
+		"(seq \"\")":                       "nil",
+		"(seq {})":                         "nil",
+		"(cons 0 [1 2])":                   "(0 1 2)",
+		"(cons 0 nil)":                     "(0)",
+		"(list? (cons 0 '(1)))":            "true",
+		"(count [1 2 3])":                  "3",
+		"(count \"héllo\")":                "5",
+		"(count {:a 1 :b 2})":              "2",
+		"(count (take 4 (range)))":         "4",
+		"(count nil)":                      "0",
+		"(nth \"abc\" 1)":                  `"b"`,
+		"(nth (range) 10)":                 "10",
+		"(nth '(1 2) 5 :none)":             ":none",
+		"(vec \"ab\")":                     `["a" "b"]`,
+		"(vec (range 3))":                  "[0 1 2]",
+		"(map inc [1 2])":                  "(2 3)",
+		"(reduce + 0 #{1 2 3})":            "6",
+		"(filter odd? (vals {:a 1 :b 2}))": "(1)",
+		"(let [[a b & more] (range)] [a b (first more)])": "[0 1 2]",
+		"(let [[a & more] [1]] [a more])":                 "[1 nil]",
+		"(= [1 2] '(1 2) (range 1 3))":                    "true",
+		"(= [1 2] '(1 2 3))":                              "false",
+		"(get {[1 2] :v} '(1 2))":                         ":v",
+		"(= (seq [1 2]) '(1 2))":                          "true",
+		"(empty? [])":                                     "true",
+		"(empty? \"a\")":                                  "false",
+	}
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	checkEval(t, in, tests)
+	if _, err := in.Eval("(first 1)"); err == nil || err.Error() != "Don't know how to create a seq from 1" {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
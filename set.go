@@ -0,0 +1,169 @@
+package clojura
+
+import (
+	"errors"
+	"strings"
+)
+
+// Set is a persistent set, stored as a map binding each element to itself.
+// Every operation returns a new set sharing structure with the old one.
+type Set struct {
+	m *HashMap
+}
+
+var emptySet = &Set{m: emptyMap}
+
+// NewSet creates a set holding items.
+func NewSet(items ...Sexpr) *Set {
+	res := emptySet
+	for _, item := range items {
+		res = res.Conj(item)
+	}
+	return res
+}
+
+func (s *Set) Length() int {
+	return s.m.Length()
+}
+
+// Contains reports whether val is an element of the set.
+func (s *Set) Contains(val Sexpr) bool {
+	_, ok := s.m.Get(val)
+	return ok
+}
+
+// Conj returns a set with val added.
+func (s *Set) Conj(val Sexpr) *Set {
+	if s.Contains(val) {
+		return s
+	}
+	return &Set{m: s.m.Assoc(val, val)}
+}
+
+// Disj returns a set without val.
+func (s *Set) Disj(val Sexpr) *Set {
+	return &Set{m: s.m.Dissoc(val)}
+}
+
+// Slice returns the elements of the set.
+func (s *Set) Slice() []Sexpr {
+	res := make([]Sexpr, 0, s.Length())
+	s.m.Each(func(key, val Sexpr) {
+		res = append(res, key)
+	})
+	return res
+}
+
+// Seq returns the elements of the set, or nil when it is empty.
+func (s *Set) Seq() Seq {
+	return seqOfSlice(s.Slice())
+}
+
+func (s *Set) Type() CoreType {
+	return TypeSet
+}
+
+func (s *Set) Append(e Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (s *Set) String() string {
+	return s.format(readableString)
+}
+
+// format prints the set, showing every element with show.
+func (s *Set) format(show func(Sexpr) string) string {
+	items := s.Slice()
+	res := make([]string, len(items))
+	for i, item := range items {
+		res[i] = show(item)
+	}
+	return "#{" + strings.Join(res, " ") + "}"
+}
+
+// Eval evaluates every element of a set literal.
+func (s *Set) Eval(c *Context) Sexpr {
+	res := emptySet
+	for _, item := range s.Slice() {
+		res = res.Conj(item.Eval(c))
+	}
+	return res
+}
+
+func (s *Set) Bool() bool {
+	return true
+}
+
+// Call looks an element up, so sets can be used as functions.
+func (s *Set) Call(args []Sexpr) Sexpr {
+	checkArity("set", args, 1)
+	if s.Contains(args[0]) {
+		return args[0]
+	}
+	return Nil
+}
+
+// setForm collects the elements of a set literal while it is parsed.
+type setForm struct {
+	Expression
+}
+
+// build turns the parsed literal into a set, rejecting duplicate elements.
+func (f *setForm) build() (*Set, error) {
+	res := emptySet
+	for _, item := range f.Elements {
+		if res.Contains(item) {
+			return nil, errors.New("Duplicate key: " + item.String())
+		}
+		res = res.Conj(item)
+	}
+	return res, nil
+}
+
+// setsEqual reports whether both sets hold the same elements.
+func setsEqual(a, b *Set) bool {
+	if a.Length() != b.Length() {
+		return false
+	}
+	for _, item := range a.Slice() {
+		if !b.Contains(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func coreHashSet(args []Sexpr) Sexpr {
+	return NewSet(args...)
+}
+
+func coreSet(args []Sexpr) Sexpr {
+	checkArity("set", args, 1)
+	if s, ok := args[0].(*Set); ok {
+		return s
+	}
+	return NewSet(seqItems(args[0])...)
+}
+
+func coreIsSet(args []Sexpr) Sexpr {
+	checkArity("set?", args, 1)
+	_, ok := args[0].(*Set)
+	return Boolean(ok)
+}
+
+func coreDisj(args []Sexpr) Sexpr {
+	if len(args) < 1 {
+		throwf("Wrong number of args (0) passed to disj")
+	}
+	if args[0] == Nil {
+		return Nil
+	}
+	s, ok := args[0].(*Set)
+	if !ok {
+		throwf("disj argument should be a set, got %s", args[0])
+	}
+	for _, arg := range args[1:] {
+		s = s.Disj(arg)
+	}
+	return s
+}
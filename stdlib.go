@@ -0,0 +1,67 @@
+package clojura
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// stdlib holds the sources of the namespaces of the standard library. A
+// namespace such as clojura.string is read from lib/clojura/string.clj.
+//
+//go:embed lib
+var stdlib embed.FS
+
+// natives holds the builtins of the namespaces of the standard library
+// written in Go. They are interned in their namespace before its source is
+// loaded.
+var natives = map[Literal]map[Literal]coreF{
+	"clojura.string": {
+		"upper-case":   stringFn("upper-case", strings.ToUpper),
+		"lower-case":   stringFn("lower-case", strings.ToLower),
+		"capitalize":   stringFn("capitalize", strCapitalize),
+		"reverse":      stringFn("reverse", strReverse),
+		"trim":         stringFn("trim", strings.TrimSpace),
+		"triml":        stringFn("triml", trimLeft),
+		"trimr":        stringFn("trimr", trimRight),
+		"includes?":    stringPred("includes?", strings.Contains),
+		"starts-with?": stringPred("starts-with?", strings.HasPrefix),
+		"ends-with?":   stringPred("ends-with?", strings.HasSuffix),
+		"blank?":       strBlank,
+		"split":        strSplit,
+		"replace":      strReplace,
+		"index-of":     strIndexOf,
+	},
+}
+
+func trimLeft(s string) string {
+	return strings.TrimLeft(s, " \t\n\r\f\v")
+}
+
+func trimRight(s string) string {
+	return strings.TrimRight(s, " \t\n\r\f\v")
+}
+
+// isBundled reports whether the file of a namespace, named relative to the
+// load path, is part of the standard library.
+func isBundled(file string) bool {
+	_, err := fs.Stat(stdlib, path.Join("lib", file))
+	return err == nil
+}
+
+// loadBundled evaluates the file of the standard library with the given
+// name, after interning the builtins of its namespace.
+func (in *Interpreter) loadBundled(file string) error {
+	f, err := stdlib.Open(path.Join("lib", file))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	name := strings.NewReplacer("/", ".", "_", "-").Replace(strings.TrimSuffix(file, ".clj"))
+	ns := in.namespace(Literal(name))
+	for sym, fn := range natives[ns.name] {
+		ns.intern(sym).value = fn
+	}
+	return in.load(f, file, file)
+}
@@ -0,0 +1,80 @@
+package clojura
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSets(t *testing.T) {
+	tests := map[string]string{
+		"(= #{1 2 3} (hash-set 3 2 1) (set [1 2 3 3]))": "true",
+		"(= #{1 2} #{1 2 3})":                           "false",
+		"(#{:a :b} :a)":                                 ":a",
+		"(#{:a :b} :c)":                                 "nil",
+		"(contains? (conj #{1} 2) 2)":                   "true",
+		"(disj #{1 2} 1)":                               "#{2}",
+		"(get #{[1 2]} [1 2])":                          "[1 2]",
+		"(let [x 1] #{x})":                              "#{1}",
+		"(set? (quote #{a}))":                           "true",
+		"(get {#{1 2} :found} #{2 1})":                  ":found",
+		"(seq #{})":                                     "nil",
+		"(seq [1 2])":                                   "(1 2)",
+		"(seq \"ab\")":                                  `("a" "b")`,
+	}
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	checkEval(t, in, tests)
+	if _, err := in.Eval("#{1 1}"); err == nil || !strings.Contains(err.Error(), "Duplicate key: 1") {
+		t.Errorf("expected a duplicate key error, got %v", err)
+	}
+}
+
+func TestStdlib(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	if _, err := in.Eval("(clojura.string/join [1])"); err == nil {
+		t.Error("expected clojura.string not to be loaded before it is required")
+	}
+	_, err := in.Eval(`(require '[clojura.string :as str] '[clojura.set :as set] '[clojura.walk :as walk])`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		`(str/join ", " [1 2 3])`:                   `"1, 2, 3"`,
+		`(str/join [])`:                             `""`,
+		`(str/upper-case "abc")`:                    `"ABC"`,
+		`(str/capitalize "hELLO")`:                  `"Hello"`,
+		`(str/trim "  a b ")`:                       `"a b"`,
+		`(str/split "a,b,c" ",")`:                   `["a" "b" "c"]`,
+		`(str/replace "a-b-c" "-" "+")`:             `"a+b+c"`,
+		`(str/index-of "abc" "z")`:                  "nil",
+		`(str/blank? " ")`:                          "true",
+		`(= (set/union #{1} #{2} #{1 3}) #{1 2 3})`: "true",
+		`(set/intersection #{1 2 3} #{2 3} #{3 4})`: "#{3}",
+		`(set/difference #{1 2 3} #{1} #{2})`:       "#{3}",
+		`(set/subset? #{1} #{1 2})`:                 "true",
+		`(set/superset? #{1} #{1 2})`:               "false",
+		`(set/select odd? #{1 2})`:                  "#{1}",
+		`(set/map-invert {:a 1})`:                   "{1 :a}",
+		`(walk/postwalk (fn [x] (if (number? x) (inc x) x)) [1 '(2 3) {:a 4}])`: "[2 (3 4) {:a 5}]",
+		`(walk/keywordize-keys {"a" [{"b" 1}]})`:                                "{:a [{:b 1}]}",
+		`(walk/stringify-keys {:a 1})`:                                          `{"a" 1}`,
+		`(walk/prewalk-replace {:x :y} [:x #{:x}])`:                             "[:y #{:y}]",
+		`(walk/postwalk identity [1 {:a '(2)}])`:                                "[1 {:a (2)}]",
+	}
+	checkEval(t, in, tests)
+}
+
+func TestCoreBasics(t *testing.T) {
+	tests := map[string]string{
+		"(identity :a)":                        ":a",
+		"(reduce + [1 2 3])":                   "6",
+		"(reduce + [])":                        "0",
+		"(reduce + 10 [1 2 3])":                "16",
+		"(reduce conj [] '(1 2))":              "[1 2]",
+		"(when (> 2 1) :a :b)":                 ":b",
+		"(when nil :a)":                        "nil",
+		"(map identity (filter odd? [1 2 3]))": "(1 3)",
+	}
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	checkEval(t, in, tests)
+}
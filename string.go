@@ -0,0 +1,222 @@
+package clojura
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// String is a string value. It evaluates to itself and prints in its quoted,
+// readable form; print and println show the raw text instead.
+type String string
+
+func (s String) Bool() bool {
+	return true
+}
+
+func (s String) String() string {
+	var res strings.Builder
+	res.WriteByte('"')
+	for _, r := range string(s) {
+		switch r {
+		case '"':
+			res.WriteString(`\"`)
+		case '\\':
+			res.WriteString(`\\`)
+		case '\n':
+			res.WriteString(`\n`)
+		case '\t':
+			res.WriteString(`\t`)
+		case '\r':
+			res.WriteString(`\r`)
+		default:
+			if r < ' ' {
+				fmt.Fprintf(&res, `\u%04x`, r)
+			} else {
+				res.WriteRune(r)
+			}
+		}
+	}
+	res.WriteByte('"')
+	return res.String()
+}
+
+func (s String) Type() CoreType {
+	return TypeString
+}
+
+func (s String) Append(e Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (s String) Eval(c *Context) Sexpr {
+	return s
+}
+
+// Seq returns the characters of the string, as one-character strings, or
+// nil when it is empty.
+func (s String) Seq() Seq {
+	var res []Sexpr
+	for _, r := range string(s) {
+		res = append(res, String(r))
+	}
+	return seqOfSlice(res)
+}
+
+// printString returns the form of s shown by print: strings nested anywhere
+// in s are written without quotes and escapes.
+func printString(s Sexpr) string {
+	switch t := s.(type) {
+	case String:
+		return string(t)
+	case *List:
+		return t.format(printString)
+	case *Vector:
+		return t.format(printString)
+	case *HashMap:
+		return t.format(printString)
+	case *Set:
+		return t.format(printString)
+	case Seq:
+		return formatSeq(t, printString)
+	}
+	return s.String()
+}
+
+// readableString returns the form of s shown by pr, which reads back as the
+// same value.
+func readableString(s Sexpr) string {
+	return s.String()
+}
+
+// joinPrinted prints every element of args with show and separates them with
+// spaces.
+func joinPrinted(args []Sexpr, show func(Sexpr) string) string {
+	res := make([]string, len(args))
+	for i, arg := range args {
+		res[i] = show(arg)
+	}
+	return strings.Join(res, " ")
+}
+
+func (in *Interpreter) corePrint(args []Sexpr) Sexpr {
+	fmt.Fprint(in.out, joinPrinted(args, printString))
+	return Nil
+}
+
+func (in *Interpreter) corePrintln(args []Sexpr) Sexpr {
+	fmt.Fprintln(in.out, joinPrinted(args, printString))
+	return Nil
+}
+
+func (in *Interpreter) corePr(args []Sexpr) Sexpr {
+	fmt.Fprint(in.out, joinPrinted(args, readableString))
+	return Nil
+}
+
+func (in *Interpreter) corePrn(args []Sexpr) Sexpr {
+	fmt.Fprintln(in.out, joinPrinted(args, readableString))
+	return Nil
+}
+
+// coreStr concatenates its arguments, skipping nil. A string argument is
+// written as is, while any other value, including the strings nested in a
+// collection, is written in its readable form.
+func coreStr(args []Sexpr) Sexpr {
+	var res strings.Builder
+	for _, arg := range args {
+		switch t := arg.(type) {
+		case nilValue:
+		case String:
+			res.WriteString(string(t))
+		default:
+			res.WriteString(readableString(arg))
+		}
+	}
+	return String(res.String())
+}
+
+func coreIsString(args []Sexpr) Sexpr {
+	checkArity("string?", args, 1)
+	_, ok := args[0].(String)
+	return Boolean(ok)
+}
+
+// stringArg raises an exception unless s is a string.
+func stringArg(name string, s Sexpr) string {
+	str, ok := s.(String)
+	if !ok {
+		throwf("%s argument should be a string, got %s", name, s)
+	}
+	return string(str)
+}
+
+// stringFn makes a clojura.string builtin of a function of one string.
+func stringFn(name string, f func(string) string) coreF {
+	return func(args []Sexpr) Sexpr {
+		checkArity(name, args, 1)
+		return String(f(stringArg(name, args[0])))
+	}
+}
+
+// stringPred makes a clojura.string builtin of a test of a string against
+// another one.
+func stringPred(name string, f func(string, string) bool) coreF {
+	return func(args []Sexpr) Sexpr {
+		checkArity(name, args, 2)
+		return Boolean(f(stringArg(name, args[0]), stringArg(name, args[1])))
+	}
+}
+
+func strBlank(args []Sexpr) Sexpr {
+	checkArity("blank?", args, 1)
+	if args[0] == Nil {
+		return True
+	}
+	return Boolean(strings.TrimSpace(stringArg("blank?", args[0])) == "")
+}
+
+func strCapitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	return strings.ToUpper(string(r[0])) + strings.ToLower(string(r[1:]))
+}
+
+func strReverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+func strSplit(args []Sexpr) Sexpr {
+	checkArity("split", args, 2)
+	parts := strings.Split(stringArg("split", args[0]), stringArg("split", args[1]))
+	res := NewVector()
+	for _, part := range parts {
+		res = res.Conj(String(part))
+	}
+	return res
+}
+
+func strReplace(args []Sexpr) Sexpr {
+	checkArity("replace", args, 3)
+	s := stringArg("replace", args[0])
+	return String(strings.ReplaceAll(s, stringArg("replace", args[1]), stringArg("replace", args[2])))
+}
+
+// strIndexOf returns the index, in characters, of the first occurrence of a
+// substring, or nil when there is none.
+func strIndexOf(args []Sexpr) Sexpr {
+	checkArity("index-of", args, 2)
+	s := stringArg("index-of", args[0])
+	i := strings.Index(s, stringArg("index-of", args[1]))
+	if i < 0 {
+		return Nil
+	}
+	return Number(utf8.RuneCountInString(s[:i]))
+}
@@ -0,0 +1,37 @@
+package clojura
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStringLiterals(t *testing.T) {
+	tests := map[string]string{
+		`"a\tb\n"`:          `"a\tb\n"`,
+		`"\u00e9\"\\"`:      `"é\"\\"`,
+		`(count "\u00e9!")`: "2",
+		`(require '[clojura.string :as s]) (s/index-of "é!" "!")`: "1",
+	}
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	checkEval(t, in, tests)
+
+	errors := map[string]string{
+		`"\u12`:    "NO_SOURCE_FILE:1:2: invalid unicode escape \\u12",
+		`"\u12" 1`: "NO_SOURCE_FILE:1:2: invalid unicode escape \\u12",
+		`"ab\q"`:   "NO_SOURCE_FILE:1:4: unsupported escape character \\q",
+		`"abc`:     "EOF while reading string",
+	}
+	checkEvalErrors(t, in, errors)
+}
+
+func TestStr(t *testing.T) {
+	tests := map[string]string{
+		`(str "a" 1 nil :b)`:    `"a1:b"`,
+		`(str [1 "a"])`:         `"[1 \"a\"]"`,
+		`(str {:k "v"} '("x"))`: `"{:k \"v\"}(\"x\")"`,
+		`(str (map inc [1 2]))`: `"(2 3)"`,
+		`(str)`:                 `""`,
+	}
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	checkEval(t, in, tests)
+}
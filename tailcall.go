@@ -0,0 +1,117 @@
+package clojura
+
+import (
+	"errors"
+)
+
+// tailForm is a form left for the caller to evaluate. Special forms return
+// it for the form in their tail position, so that a function can evaluate
+// that form after the special form has returned.
+type tailForm struct {
+	form    Sexpr
+	context *Context
+}
+
+// inTail returns form to be evaluated in c by the caller.
+func inTail(c *Context, form Sexpr) Sexpr {
+	return &tailForm{form: form, context: c}
+}
+
+func (t *tailForm) Type() CoreType {
+	return TypeTailCall
+}
+
+func (t *tailForm) Bool() bool {
+	return true
+}
+
+func (t *tailForm) String() string {
+	return t.form.String()
+}
+
+func (t *tailForm) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (t *tailForm) Eval(c *Context) Sexpr {
+	return force(t)
+}
+
+// tailCall is a call in tail position left for the caller to make. A
+// function calling another function this way runs it in its own loop
+// instead of growing the Go stack.
+type tailCall struct {
+	fn     Function
+	args   []Sexpr
+	frame  Frame
+	interp *Interpreter
+}
+
+func (t *tailCall) Type() CoreType {
+	return TypeTailCall
+}
+
+func (t *tailCall) Bool() bool {
+	return true
+}
+
+func (t *tailCall) String() string {
+	return "call " + t.frame.Name
+}
+
+func (t *tailCall) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (t *tailCall) Eval(c *Context) Sexpr {
+	return force(t)
+}
+
+// call makes the call with its frame on the stack.
+func (t *tailCall) call() Sexpr {
+	in := t.interp
+	in.pushFrame(t.frame)
+	res := t.fn.Call(t.args)
+	in.popFrame()
+	return res
+}
+
+// tailEvaler is implemented by forms which can leave the forms and calls in
+// their tail position to the caller.
+type tailEvaler interface {
+	eval(c *Context, tail bool) Sexpr
+}
+
+// evalTail evaluates form in tail position: calls are returned as tail calls
+// rather than made.
+func evalTail(c *Context, form Sexpr) Sexpr {
+	if t, ok := form.(tailEvaler); ok {
+		return t.eval(c, true)
+	}
+	return form.Eval(c)
+}
+
+// force evaluates the tail forms and makes the tail calls left in s.
+func force(s Sexpr) Sexpr {
+	for {
+		switch t := s.(type) {
+		case *tailForm:
+			s = evalTail(t.context, t.form)
+		case *tailCall:
+			s = t.call()
+		default:
+			return s
+		}
+	}
+}
+
+func coreTrampoline(args []Sexpr) Sexpr {
+	if len(args) < 1 {
+		throwf("Wrong number of args (0) passed to trampoline")
+	}
+	res := call(args[0], args[1:])
+	for res.Type() == TypeFunction {
+		res = call(res, nil)
+	}
+	return res
+}
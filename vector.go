@@ -0,0 +1,452 @@
+package clojura
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	vectorBits  = 5
+	vectorWidth = 1 << vectorBits
+	vectorMask  = vectorWidth - 1
+)
+
+// vectorNode is a node of the vector trie. Branches only use children and
+// leaves only use values.
+type vectorNode struct {
+	children []*vectorNode
+	values   []Sexpr
+}
+
+func newBranch() *vectorNode {
+	return &vectorNode{children: make([]*vectorNode, vectorWidth)}
+}
+
+func (n *vectorNode) clone() *vectorNode {
+	c := &vectorNode{}
+	if n.children != nil {
+		c.children = make([]*vectorNode, vectorWidth)
+		copy(c.children, n.children)
+	}
+	if n.values != nil {
+		c.values = make([]Sexpr, len(n.values))
+		copy(c.values, n.values)
+	}
+	return c
+}
+
+// Vector is a persistent vector: a 32-way trie holding all elements but the
+// last few, which are kept in a tail for fast appends. Every operation
+// returns a new vector sharing structure with the old one.
+type Vector struct {
+	cnt   int
+	shift uint
+	root  *vectorNode
+	tail  []Sexpr
+}
+
+var emptyVector = &Vector{
+	shift: vectorBits,
+	root:  newBranch(),
+	tail:  []Sexpr{},
+}
+
+// NewVector creates a vector holding items.
+func NewVector(items ...Sexpr) *Vector {
+	v := *emptyVector
+	res := &v
+	for _, item := range items {
+		res = res.Conj(item)
+	}
+	return res
+}
+
+func (v *Vector) Length() int {
+	return v.cnt
+}
+
+func (v *Vector) tailoff() int {
+	if v.cnt < vectorWidth {
+		return 0
+	}
+	return ((v.cnt - 1) >> vectorBits) << vectorBits
+}
+
+// leafFor returns the leaf (or tail) holding the i-th element.
+func (v *Vector) leafFor(i int) []Sexpr {
+	if i >= v.tailoff() {
+		return v.tail
+	}
+	node := v.root
+	for level := v.shift; level > 0; level -= vectorBits {
+		node = node.children[(i>>level)&vectorMask]
+	}
+	return node.values
+}
+
+// Nth returns the i-th element and reports false if i is out of bounds.
+func (v *Vector) Nth(i int) (Sexpr, bool) {
+	if i < 0 || i >= v.cnt {
+		return nil, false
+	}
+	return v.leafFor(i)[i&vectorMask], true
+}
+
+// Slice returns the elements of the vector.
+func (v *Vector) Slice() []Sexpr {
+	res := make([]Sexpr, 0, v.cnt)
+	for i := 0; i < v.cnt; i += vectorWidth {
+		res = append(res, v.leafFor(i)...)
+	}
+	return res
+}
+
+// Seq returns the elements of the vector, or nil when it is empty.
+func (v *Vector) Seq() Seq {
+	if v.cnt == 0 {
+		return nil
+	}
+	return &vectorSeq{v: v}
+}
+
+// vectorSeq is a sequence over the elements of a vector from the i-th one
+// on.
+type vectorSeq struct {
+	v *Vector
+	i int
+}
+
+func (s *vectorSeq) Seq() Seq {
+	if s.i >= s.v.cnt {
+		return nil
+	}
+	return s
+}
+
+func (s *vectorSeq) First() Sexpr {
+	if res, ok := s.v.Nth(s.i); ok {
+		return res
+	}
+	return Nil
+}
+
+func (s *vectorSeq) Rest() Seq {
+	if s.i >= s.v.cnt {
+		return s
+	}
+	return &vectorSeq{s.v, s.i + 1}
+}
+
+func (s *vectorSeq) Type() CoreType {
+	return TypeList
+}
+
+func (s *vectorSeq) Append(e Sexpr) error {
+	return errors.New("cannot append")
+}
+
+func (s *vectorSeq) String() string {
+	return formatSeq(s, readableString)
+}
+
+func (s *vectorSeq) Eval(c *Context) Sexpr {
+	return s
+}
+
+func (s *vectorSeq) Bool() bool {
+	return true
+}
+
+// Conj returns a vector with val added to the end.
+func (v *Vector) Conj(val Sexpr) *Vector {
+	if v.cnt-v.tailoff() < vectorWidth {
+		tail := make([]Sexpr, len(v.tail)+1)
+		copy(tail, v.tail)
+		tail[len(v.tail)] = val
+		return &Vector{cnt: v.cnt + 1, shift: v.shift, root: v.root, tail: tail}
+	}
+
+	// The tail is full, so it moves into the trie.
+	tailNode := &vectorNode{values: v.tail}
+	shift := v.shift
+	var root *vectorNode
+	if (v.cnt >> vectorBits) > (1 << v.shift) {
+		root = newBranch()
+		root.children[0] = v.root
+		root.children[1] = newPath(v.shift, tailNode)
+		shift += vectorBits
+	} else {
+		root = v.pushTail(v.shift, v.root, tailNode)
+	}
+	return &Vector{cnt: v.cnt + 1, shift: shift, root: root, tail: []Sexpr{val}}
+}
+
+func (v *Vector) pushTail(level uint, parent, tailNode *vectorNode) *vectorNode {
+	subidx := ((v.cnt - 1) >> level) & vectorMask
+	res := parent.clone()
+	if level == vectorBits {
+		res.children[subidx] = tailNode
+	} else if child := parent.children[subidx]; child != nil {
+		res.children[subidx] = v.pushTail(level-vectorBits, child, tailNode)
+	} else {
+		res.children[subidx] = newPath(level-vectorBits, tailNode)
+	}
+	return res
+}
+
+func newPath(level uint, node *vectorNode) *vectorNode {
+	if level == 0 {
+		return node
+	}
+	res := newBranch()
+	res.children[0] = newPath(level-vectorBits, node)
+	return res
+}
+
+// Assoc returns a vector with the i-th element replaced by val. An index
+// equal to the length appends val.
+func (v *Vector) Assoc(i int, val Sexpr) (*Vector, bool) {
+	if i == v.cnt {
+		return v.Conj(val), true
+	}
+	if i < 0 || i > v.cnt {
+		return nil, false
+	}
+	if i >= v.tailoff() {
+		tail := make([]Sexpr, len(v.tail))
+		copy(tail, v.tail)
+		tail[i&vectorMask] = val
+		return &Vector{cnt: v.cnt, shift: v.shift, root: v.root, tail: tail}, true
+	}
+	root := doAssoc(v.shift, v.root, i, val)
+	return &Vector{cnt: v.cnt, shift: v.shift, root: root, tail: v.tail}, true
+}
+
+func doAssoc(level uint, node *vectorNode, i int, val Sexpr) *vectorNode {
+	res := node.clone()
+	if level == 0 {
+		res.values[i&vectorMask] = val
+	} else {
+		subidx := (i >> level) & vectorMask
+		res.children[subidx] = doAssoc(level-vectorBits, node.children[subidx], i, val)
+	}
+	return res
+}
+
+// Peek returns the last element of the vector.
+func (v *Vector) Peek() Sexpr {
+	if v.cnt == 0 {
+		return Nil
+	}
+	return v.tail[len(v.tail)-1]
+}
+
+// Pop returns a vector without its last element.
+func (v *Vector) Pop() *Vector {
+	if v.cnt == 0 {
+		throwf("Can't pop empty vector")
+	}
+	if v.cnt == 1 {
+		return NewVector()
+	}
+	if v.cnt-v.tailoff() > 1 {
+		tail := make([]Sexpr, len(v.tail)-1)
+		copy(tail, v.tail)
+		return &Vector{cnt: v.cnt - 1, shift: v.shift, root: v.root, tail: tail}
+	}
+
+	// The tail becomes empty, so the last leaf of the trie takes its place.
+	tail := v.leafFor(v.cnt - 2)
+	root := v.popTail(v.shift, v.root)
+	shift := v.shift
+	if root == nil {
+		root = newBranch()
+	}
+	if shift > vectorBits && root.children[1] == nil {
+		root = root.children[0]
+		shift -= vectorBits
+	}
+	return &Vector{cnt: v.cnt - 1, shift: shift, root: root, tail: tail}
+}
+
+func (v *Vector) popTail(level uint, node *vectorNode) *vectorNode {
+	subidx := ((v.cnt - 2) >> level) & vectorMask
+	if level > vectorBits {
+		child := v.popTail(level-vectorBits, node.children[subidx])
+		if child == nil && subidx == 0 {
+			return nil
+		}
+		res := node.clone()
+		res.children[subidx] = child
+		return res
+	} else if subidx == 0 {
+		return nil
+	}
+	res := node.clone()
+	res.children[subidx] = nil
+	return res
+}
+
+func (v *Vector) Type() CoreType {
+	return TypeVector
+}
+
+func (v *Vector) Append(s Sexpr) error {
+	return errors.New("cannot append")
+}
+
+// vectorForm collects the elements of a vector literal while it is parsed.
+type vectorForm struct {
+	Expression
+}
+
+func (v *Vector) String() string {
+	return v.format(readableString)
+}
+
+// format prints the vector, showing every element with show.
+func (v *Vector) format(show func(Sexpr) string) string {
+	items := v.Slice()
+	res := make([]string, len(items))
+	for i, item := range items {
+		res[i] = show(item)
+	}
+	return "[" + strings.Join(res, " ") + "]"
+}
+
+// Eval evaluates every element of a vector literal.
+func (v *Vector) Eval(c *Context) Sexpr {
+	res := NewVector()
+	for _, item := range v.Slice() {
+		res = res.Conj(item.Eval(c))
+	}
+	return res
+}
+
+func (v *Vector) Bool() bool {
+	return true
+}
+
+// Call looks an element up by index, so vectors can be used as functions.
+func (v *Vector) Call(args []Sexpr) Sexpr {
+	checkArity("vector", args, 1)
+	return nth(v, numberArg("nth", args[0]))
+}
+
+// nth returns the i-th element of a vector, a string or a sequence.
+// nth returns the element of coll at index i. Like any index of nil, it
+// returns nil for nil.
+func nth(coll Sexpr, i Number) Sexpr {
+	if coll == Nil {
+		return Nil
+	}
+	res, ok := lookupNth(coll, i)
+	if !ok {
+		throwf("Index %d out of bounds", i)
+	}
+	return res
+}
+
+func lookupNth(coll Sexpr, i Number) (Sexpr, bool) {
+	switch t := coll.(type) {
+	case *Vector:
+		return t.Nth(int(i))
+	case String, Seq, nilValue:
+		if i < 0 {
+			return nil, false
+		}
+		s := seqOf(t)
+		for ; s != nil && i > 0; i-- {
+			s = s.Rest().Seq()
+		}
+		if s == nil {
+			return nil, false
+		}
+		return s.First(), true
+	}
+	throwf("nth not supported on %s", coll)
+	return nil, false
+}
+
+// vectorArg raises an exception unless s is a vector.
+func vectorArg(name string, s Sexpr) *Vector {
+	v, ok := s.(*Vector)
+	if !ok {
+		throwf("%s argument should be a vector, got %s", name, s)
+	}
+	return v
+}
+
+func coreVector(args []Sexpr) Sexpr {
+	return NewVector(args...)
+}
+
+func coreVec(args []Sexpr) Sexpr {
+	checkArity("vec", args, 1)
+	if v, ok := args[0].(*Vector); ok {
+		return v
+	}
+	return NewVector(seqItems(args[0])...)
+}
+
+func coreIsVector(args []Sexpr) Sexpr {
+	checkArity("vector?", args, 1)
+	_, ok := args[0].(*Vector)
+	return Boolean(ok)
+}
+
+func coreNth(args []Sexpr) Sexpr {
+	if len(args) != 2 && len(args) != 3 {
+		throwf("Wrong number of args (%d) passed to nth", len(args))
+	}
+	i := numberArg("nth", args[1])
+	if len(args) == 3 {
+		if res, ok := lookupNth(args[0], i); ok {
+			return res
+		}
+		return args[2]
+	}
+	return nth(args[0], i)
+}
+
+func coreSubvec(args []Sexpr) Sexpr {
+	if len(args) != 2 && len(args) != 3 {
+		throwf("Wrong number of args (%d) passed to subvec", len(args))
+	}
+	v := vectorArg("subvec", args[0])
+	start, end := int(numberArg("subvec", args[1])), v.Length()
+	if len(args) == 3 {
+		end = int(numberArg("subvec", args[2]))
+	}
+	if start < 0 || end > v.Length() || start > end {
+		throwf("Index out of bounds for subvec: %d %d", start, end)
+	}
+	return NewVector(v.Slice()[start:end]...)
+}
+
+func corePeek(args []Sexpr) Sexpr {
+	checkArity("peek", args, 1)
+	switch t := args[0].(type) {
+	case *Vector:
+		return t.Peek()
+	case *List:
+		return t.Head()
+	}
+	throwf("peek argument should be a vector or a list, got %s", args[0])
+	return nil
+}
+
+func corePop(args []Sexpr) Sexpr {
+	checkArity("pop", args, 1)
+	switch t := args[0].(type) {
+	case *Vector:
+		return t.Pop()
+	case *List:
+		if t.Length() == 0 {
+			throwf("Can't pop empty list")
+		}
+		return t.GetTail()
+	}
+	throwf("pop argument should be a vector or a list, got %s", args[0])
+	return nil
+}
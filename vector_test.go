@@ -0,0 +1,85 @@
+package clojura
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVectorConjAndPop(t *testing.T) {
+	const n = 40000
+	v := NewVector()
+	for i := 0; i < n; i++ {
+		v = v.Conj(Number(i))
+	}
+	if v.Length() != n {
+		t.Fatalf("expected %d elements, got %d", n, v.Length())
+	}
+	for i := 0; i < n; i++ {
+		if el, _ := v.Nth(i); el != Number(i) {
+			t.Fatalf("expected %d at %d, got %v", i, i, el)
+		}
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		if v.Peek() != Number(i) {
+			t.Fatalf("expected %d on top, got %v", i, v.Peek())
+		}
+		v = v.Pop()
+	}
+	if v.Length() != 0 {
+		t.Fatalf("expected an empty vector, got %d elements", v.Length())
+	}
+}
+
+func TestVectorAssocKeepsOriginal(t *testing.T) {
+	v := NewVector()
+	for i := 0; i < 2000; i++ {
+		v = v.Conj(Number(i))
+	}
+	for _, i := range []int{0, 31, 32, 1023, 1024, 1999} {
+		changed, ok := v.Assoc(i, String("x"))
+		if !ok {
+			t.Fatalf("failed to assoc %d", i)
+		}
+		if el, _ := changed.Nth(i); el != String("x") {
+			t.Errorf("expected x at %d, got %v", i, el)
+		}
+		if el, _ := v.Nth(i); el != Number(i) {
+			t.Errorf("original vector changed at %d: %v", i, el)
+		}
+	}
+}
+
+func TestEmptyVectorNotShared(t *testing.T) {
+	a := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	b := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	v, err := a.Eval("[]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Append(Number(99)) == nil {
+		t.Error("expected vectors not to be appended to")
+	}
+	checkEval(t, b, map[string]string{
+		"(count [])": "0",
+		"(pop [1])":  "[]",
+		"[]":         "[]",
+		"[1 [2] []]": "[1 [2] []]",
+	})
+}
+
+func TestNth(t *testing.T) {
+	tests := map[string]string{
+		"(nth [1 2 3] 1)":     "2",
+		"(nth '(1 2 3) 2)":    "3",
+		"(nth [1] 5 :none)":   ":none",
+		"(nth nil 0)":         "nil",
+		"(nth nil 0 :none)":   ":none",
+		"(let [[a b] nil] b)": "nil",
+	}
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	checkEval(t, in, tests)
+	if _, err := in.Eval("(nth [1] 5)"); err == nil {
+		t.Error("expected an index out of bounds error")
+	}
+}
@@ -0,0 +1,159 @@
+package clojura
+
+// operand reads the two byte operand at pc.
+func operand(code []byte, pc int) int {
+	return int(code[pc])<<8 | int(code[pc+1])
+}
+
+// run calls the function on the VM. A call in tail position to another
+// function replaces it on the stack instead of growing the Go stack.
+func (f *function) run(args []Sexpr) Sexpr {
+	in := f.context.interp
+	fn := f
+	pushed := false
+	for {
+		a := fn.arityFor(len(args))
+		res := in.execute(a.compiled(), fn.enter(a, args, false), fn)
+		if t, ok := res.(*tailCall); ok {
+			if g, ok := t.fn.(*function); ok {
+				if pushed {
+					in.stack[len(in.stack)-1] = t.frame
+				} else {
+					in.pushFrame(t.frame)
+					pushed = true
+				}
+				fn, args = g, t.args
+				continue
+			}
+			res = t.call()
+		}
+		if pushed {
+			in.popFrame()
+		}
+		return res
+	}
+}
+
+// execute runs the code of ch in frame. fn is the function whose arity the
+// code belongs to, or nil for top-level code. Calls in tail position are
+// returned as tail calls for run to make.
+func (in *Interpreter) execute(ch *chunk, frame *Context, fn *function) Sexpr {
+	code := ch.code
+	stack := make([]Sexpr, 0, 16)
+	pc := 0
+	for {
+		switch opcode(code[pc]) {
+		case opConst:
+			stack = append(stack, ch.consts[operand(code, pc+1)])
+			pc += 3
+		case opLocal:
+			c := frame
+			for depth := operand(code, pc+1); depth > 0; depth-- {
+				c = c.parent
+			}
+			stack = append(stack, c.slots[operand(code, pc+3)])
+			pc += 5
+		case opVar:
+			v := ch.vars[operand(code, pc+1)]
+			if v.value == nil {
+				throwf("Var %s is unbound", v.name)
+			}
+			stack = append(stack, v.value)
+			pc += 3
+		case opDef:
+			ch.vars[operand(code, pc+1)].value = stack[len(stack)-1]
+			pc += 3
+		case opPop:
+			stack = stack[:len(stack)-1]
+			pc++
+		case opJump:
+			pc = operand(code, pc+1)
+		case opJumpFalse:
+			val := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if val.Bool() {
+				pc += 3
+			} else {
+				pc = operand(code, pc+1)
+			}
+		case opAnd, opOr:
+			if stack[len(stack)-1].Bool() == (opcode(code[pc]) == opOr) {
+				pc = operand(code, pc+1)
+			} else {
+				stack = stack[:len(stack)-1]
+				pc += 3
+			}
+		case opCall, opTailCall:
+			n, site := operand(code, pc+1), ch.sites[operand(code, pc+3)]
+			base := len(stack) - n - 1
+			f := stack[base]
+			fun, ok := f.(Function)
+			if !ok {
+				throwf("%s is not a function", site.head)
+			}
+			args := make([]Sexpr, n)
+			copy(args, stack[base+1:])
+			stack = stack[:base]
+			call := Frame{Name: site.name, Pos: site.pos}
+			if g, ok := f.(*function); ok && g.name != "" {
+				call.Name = g.name
+			}
+			if opcode(code[pc]) == opTailCall {
+				return &tailCall{fn: fun, args: args, frame: call, interp: in}
+			}
+			in.pushFrame(call)
+			res := fun.Call(args)
+			in.popFrame()
+			stack = append(stack, res)
+			pc += 5
+		case opClosure:
+			stack = append(stack, ch.fns[operand(code, pc+1)].Eval(frame))
+			pc += 3
+		case opVector:
+			n := operand(code, pc+1)
+			v := NewVector(stack[len(stack)-n:]...)
+			stack = append(stack[:len(stack)-n], v)
+			pc += 3
+		case opMap:
+			n := 2 * operand(code, pc+1)
+			m := NewHashMap(stack[len(stack)-n:]...)
+			stack = append(stack[:len(stack)-n], m)
+			pc += 3
+		case opEnter:
+			frame = newFrame(frame, operand(code, pc+1))
+			pc += 3
+		case opLeave:
+			frame = frame.parent
+			pc++
+		case opBind:
+			val := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			ch.bindings[operand(code, pc+1)].bind(frame, val)
+			pc += 3
+		case opRecur:
+			n := operand(code, pc+1)
+			args := make([]Sexpr, n)
+			copy(args, stack[len(stack)-n:])
+			stack = stack[:0]
+			frame = fn.enter(ch.arity, args, true)
+			pc = 0
+		case opLoop:
+			l := ch.loops[operand(code, pc+1)]
+			for depth := operand(code, pc+3); depth > 0; depth-- {
+				frame = frame.parent
+			}
+			frame = newFrame(frame.parent, l.node.size)
+			n := len(l.node.bindings)
+			for i, b := range l.node.bindings {
+				b.bind(frame, stack[len(stack)-n+i])
+			}
+			stack = stack[:len(stack)-n]
+			pc = l.start
+		case opEval:
+			stack = append(stack, ch.consts[operand(code, pc+1)].Eval(frame))
+			pc += 3
+		case opReturn:
+			return stack[len(stack)-1]
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package clojura
+
+import (
+	"bytes"
+	"runtime/debug"
+	"testing"
+)
+
+func TestVM(t *testing.T) {
+	// Tail calls in the last test need far more stack without the VM's own.
+	defer debug.SetMaxStack(debug.SetMaxStack(8 << 20))
+
+	tests := map[string]string{
+		"(if (< 1 2) :yes :no)": ":yes",
+		"(if nil 1)":            "nil",
+		"[(and) (and 1 nil 2) (and 1 2) (or) (or nil 2) (or false nil)]":                                     "[true nil 2 nil 2 nil]",
+		"(let [[a & more] [1 2 3] {:keys [x] :or {x 5}} {}] [a more x])":                                     "[1 (2 3) 5]",
+		"(loop [i 0 acc []] (let [j (+ i 1)] (if (< i 3) (recur j (conj acc {i j})) acc)))":                  "[{0 1} {1 2} {2 3}]",
+		"(((fn [a] (fn [b] (+ a b))) 1) 2)":                                                                  "3",
+		"(letfn [(f [n] (if (= n 0) :f (g (- n 1)))) (g [n] (f n))] (f 3))":                                  ":f",
+		"(do (def sum (fn [acc & xs] (if (seq xs) (recur (+ acc (head xs)) (tail xs)) acc))) (sum 0 1 2 3))": "6",
+		"(try (throw (ex-info \"boom\" {})) (catch Exception e (ex-message e)))":                             `"boom"`,
+		"(defmacro unless [c x] `(if ~c nil ~x)) (unless false `[~(+ 1 2)])":                                 "[3]",
+		`(do (declare od?)
+		     (def ev? (fn [n] (if (= n 0) true (od? (- n 1)))))
+		     (def od? (fn [n] (if (= n 0) false (ev? (- n 1)))))
+		     (ev? 100001))`: "false",
+	}
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	in.UseVM(true)
+	checkEval(t, in, tests)
+}
+
+func TestVMExceptionTrace(t *testing.T) {
+	in := NewInterpreter(&bytes.Buffer{}, &bytes.Buffer{})
+	in.UseVM(true)
+	_, err := in.Eval("(def f (fn (x)\n  (+ x \"a\")))\n(f 1)")
+	e, ok := err.(*Exception)
+	if !ok {
+		t.Fatalf("expected an exception, got %v", err)
+	}
+	expected := []string{
+		"at + (NO_SOURCE_FILE:2:3)",
+		"at f (NO_SOURCE_FILE:3:1)",
+	}
+	if len(e.Trace) != len(expected) {
+		t.Fatalf("unexpected trace %v", e.Trace)
+	}
+	for i, frame := range e.Trace {
+		if frame.String() != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], frame)
+		}
+	}
+}